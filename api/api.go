@@ -0,0 +1,110 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/metachris/flashbots/logging"
+)
+
+// BaseUrl is the base URL of the mev-blocks API, without trailing slash
+var BaseUrl string = "https://blocks.flashbots.net"
+
+const DefaultTimeout = 5 * time.Second
+
+// HttpClient is used for all mev-blocks API requests (see SetTimeout)
+var HttpClient = &http.Client{Timeout: DefaultTimeout}
+
+// ErrTimeout is returned (wrapped, use errors.Is) if a mev-blocks API request timed out
+var ErrTimeout = errors.New("mev-blocks api timeout")
+
+// SetTimeout sets the timeout of mev-blocks API requests, including reading the response (0 means no timeout)
+func SetTimeout(timeout time.Duration) error {
+	if timeout < 0 {
+		return fmt.Errorf("invalid Flashbots API timeout: %s", timeout)
+	}
+
+	HttpClient.Timeout = timeout
+	return nil
+}
+
+// wrapRequestError returns ErrTimeout (wrapped) for timeouts, or err
+func wrapRequestError(err error, url string, prefix string) error {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return fmt.Errorf("%w after %s: %s", ErrTimeout, HttpClient.Timeout, url)
+	}
+	return fmt.Errorf("%s: %s - %w", prefix, url, err)
+}
+
+// StrictResponses treats mev-blocks API responses with values of unexpected types, or without expected fields, as
+// errors (ErrInvalidResponse). By default they are decoded as far as possible and a warning is logged, so a schema
+// change of the API doesn't stop the checks.
+var StrictResponses bool
+
+// ErrInvalidResponse is returned (wrapped, use errors.Is) for unexpected responses with StrictResponses
+var ErrInvalidResponse = errors.New("mev-blocks api unexpected response")
+
+// decodeResponse decodes the JSON body into response. encoding/json skips values of unexpected types and decodes the
+// rest, so these are only an error with StrictResponses.
+func decodeResponse(body io.Reader, response interface{}, url string) error {
+	err := json.NewDecoder(body).Decode(response)
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		if StrictResponses {
+			return fmt.Errorf("%w: %s - %v", ErrInvalidResponse, url, err)
+		}
+		logging.Log.Warnw("Unexpected mev-blocks api response, decoded without the affected values", "url", url, "error", err)
+		return nil
+	}
+	if err != nil {
+		return wrapRequestError(err, url, "mev-blocks api response decode error")
+	}
+	return nil
+}
+
+// checkMissingFields returns an error (with StrictResponses), or logs a warning, if expected fields are missing
+func checkMissingFields(missingFields []string, url string) error {
+	if len(missingFields) == 0 {
+		return nil
+	}
+
+	if StrictResponses {
+		return fmt.Errorf("%w: %s - missing fields: %s", ErrInvalidResponse, url, strings.Join(missingFields, ", "))
+	}
+	logging.Log.Warnw("Unexpected mev-blocks api response, missing fields", "url", url, "fields", strings.Join(missingFields, ", "))
+	return nil
+}
+
+// missingFieldList collects the names of missing fields (each name once, in the order found)
+type missingFieldList []string
+
+func (l *missingFieldList) check(isMissing bool, name string) {
+	if !isMissing {
+		return
+	}
+	for _, field := range *l {
+		if field == name {
+			return
+		}
+	}
+	*l = append(*l, name)
+}
+
+// SetBaseUrl sets the base URL of the mev-blocks API (i.e. for alternative relays or testing), after validating it
+func SetBaseUrl(baseUrl string) error {
+	u, err := url.Parse(baseUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid Flashbots API URL: %s", baseUrl)
+	}
+
+	BaseUrl = strings.TrimSuffix(baseUrl, "/")
+	return nil
+}
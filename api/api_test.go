@@ -2,7 +2,13 @@
 package api_test
 
 import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/metachris/flashbots/api"
 )
@@ -68,3 +74,121 @@ func TestTransactionsApi(t *testing.T) {
 		t.Error("Wrong amount of tx:", len(txs.Transactions), "wanted:", 5)
 	}
 }
+
+func TestSetBaseUrl(t *testing.T) {
+	defaultBaseUrl := api.BaseUrl
+	defer func() { api.BaseUrl = defaultBaseUrl }()
+
+	for _, invalidUrl := range []string{"", "blocks.flashbots.net", "ftp://blocks.flashbots.net", "https://"} {
+		if err := api.SetBaseUrl(invalidUrl); err == nil {
+			t.Error("Expected error for invalid URL:", invalidUrl)
+		}
+	}
+
+	var requestPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestPath = r.URL.RequestURI()
+		w.Write([]byte(`{"latest_block_number": 123, "blocks": []}`))
+	}))
+	defer server.Close()
+
+	if err := api.SetBaseUrl(server.URL + "/"); err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := api.GetBlocks(&api.GetBlocksOptions{BlockNumber: 123})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requestPath != "/v1/blocks?block_number=123" {
+		t.Error("Wrong request path:", requestPath, "wanted:", "/v1/blocks?block_number=123")
+	}
+	if response.LatestBlockNumber != 123 {
+		t.Error("Wrong LatestBlockNumber:", response.LatestBlockNumber, "wanted:", 123)
+	}
+}
+
+func TestGetBlocksTimeout(t *testing.T) {
+	defaultBaseUrl := api.BaseUrl
+	defer func() {
+		api.BaseUrl = defaultBaseUrl
+		api.SetTimeout(api.DefaultTimeout)
+	}()
+
+	done := make(chan bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done // hanging relay
+	}))
+	defer server.Close()
+	defer close(done)
+
+	api.SetBaseUrl(server.URL)
+	if err := api.SetTimeout(50 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := api.GetBlocks(&api.GetBlocksOptions{BlockNumber: 123})
+	if !errors.Is(err, api.ErrTimeout) {
+		t.Error("Wrong error:", err, "wanted:", api.ErrTimeout)
+	}
+
+	if err := api.SetTimeout(-time.Second); err == nil {
+		t.Error("Expected error for negative timeout")
+	}
+}
+
+func TestGetBlocksResponseShape(t *testing.T) {
+	defaultBaseUrl := api.BaseUrl
+	defer func() {
+		api.BaseUrl = defaultBaseUrl
+		api.StrictResponses = false
+	}()
+
+	fixture, err := ioutil.ReadFile("testdata/blocks_response.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	}))
+	defer server.Close()
+	api.SetBaseUrl(server.URL)
+
+	// Fixture in the expected shape
+	body = fixture
+	for _, strict := range []bool{false, true} {
+		api.StrictResponses = strict
+		response, err := api.GetBlocks(&api.GetBlocksOptions{BlockNumber: 12705543})
+		if err != nil {
+			t.Fatal("Strict:", strict, err)
+		}
+		if !response.HasTx("0x1e8d4d2b4bb6e5d3a0c1a1b9b3d4e6e0c7c3e6f2f9e6a7a8c3b2e1d0f9e8d7c6") {
+			t.Error("Flashbots tx not found in response, strict:", strict)
+		}
+	}
+
+	// Schema changes: a renamed field, and a field of another type
+	changedResponses := map[string][]byte{
+		"renamed field": bytes.Replace(fixture, []byte(`"transaction_hash"`), []byte(`"hash"`), -1),
+		"changed type":  bytes.Replace(fixture, []byte(`"block_number": 12705543,`), []byte(`"block_number": "12705543",`), 1),
+	}
+	for name, changedResponse := range changedResponses {
+		body = changedResponse
+
+		api.StrictResponses = false
+		response, err := api.GetBlocks(&api.GetBlocksOptions{BlockNumber: 12705543})
+		if err != nil {
+			t.Error("Expected no error without -strict-flashbots for", name, "-", err)
+		}
+		if response.LatestBlockNumber != 12705600 {
+			t.Error("Wrong LatestBlockNumber for", name, "-", response.LatestBlockNumber, "wanted:", 12705600)
+		}
+
+		api.StrictResponses = true
+		if _, err = api.GetBlocks(&api.GetBlocksOptions{BlockNumber: 12705543}); !errors.Is(err, api.ErrInvalidResponse) {
+			t.Error("Wrong error for", name, "-", err, "wanted:", api.ErrInvalidResponse)
+		}
+	}
+}
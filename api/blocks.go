@@ -1,12 +1,48 @@
 package api
 
 import (
-	"encoding/json"
 	"fmt"
-	"net/http"
 	"strings"
+	"sync"
+	"time"
 )
 
+// GetBlocksCacheTTL is the duration for which GetBlocks reuses a previous response for the same request (0 disables caching)
+var GetBlocksCacheTTL time.Duration
+
+type cachedGetBlocksResponse struct {
+	Time     time.Time
+	Response GetBlocksResponse
+}
+
+var getBlocksCache map[string]cachedGetBlocksResponse = make(map[string]cachedGetBlocksResponse)
+var getBlocksCacheLock sync.Mutex
+
+func getCachedBlocksResponse(url string) (response GetBlocksResponse, found bool) {
+	getBlocksCacheLock.Lock()
+	defer getBlocksCacheLock.Unlock()
+
+	cached, found := getBlocksCache[url]
+	if !found || time.Since(cached.Time) > GetBlocksCacheTTL {
+		return response, false
+	}
+	return cached.Response, true
+}
+
+func addBlocksResponseToCache(url string, response GetBlocksResponse) {
+	getBlocksCacheLock.Lock()
+	defer getBlocksCacheLock.Unlock()
+
+	// Remove expired entries
+	for key, cached := range getBlocksCache {
+		if time.Since(cached.Time) > GetBlocksCacheTTL {
+			delete(getBlocksCache, key)
+		}
+	}
+
+	getBlocksCache[url] = cachedGetBlocksResponse{Time: time.Now(), Response: response}
+}
+
 type FlashbotsBlock struct {
 	BlockNumber       int64  `json:"block_number"`
 	Miner             string `json:"miner"`
@@ -67,6 +103,18 @@ type GetBlocksResponse struct {
 	Blocks            []FlashbotsBlock `json:"blocks"`
 }
 
+// missingFields returns the names of the fields that are needed to classify Flashbots tx, but missing (or zero)
+func (r *GetBlocksResponse) missingFields() (fields missingFieldList) {
+	fields.check(r.LatestBlockNumber == 0, "latest_block_number")
+	for _, block := range r.Blocks {
+		fields.check(block.BlockNumber == 0, "blocks.block_number")
+		for _, tx := range block.Transactions {
+			tx.checkFields(&fields, "blocks.transactions.")
+		}
+	}
+	return fields
+}
+
 // GetTxMap returns a map of all transactions, indexed by hash
 func (r *GetBlocksResponse) GetTxMap() map[string]FlashbotsTransaction {
 	res := make(map[string]FlashbotsTransaction)
@@ -89,27 +137,41 @@ func (r *GetBlocksResponse) HasTx(hash string) bool {
 // part of the flashbots bundle.
 // https://blocks.flashbots.net/v1/blocks
 func GetBlocks(options *GetBlocksOptions) (response GetBlocksResponse, err error) {
-	url := "https://blocks.flashbots.net/v1/blocks"
+	url := BaseUrl + "/v1/blocks"
 	if options != nil {
 		url = url + options.ToUriQuery()
 	}
 
-	resp, err := http.Get(url)
+	if GetBlocksCacheTTL > 0 {
+		if cachedResponse, found := getCachedBlocksResponse(url); found {
+			return cachedResponse, nil
+		}
+	}
+
+	resp, err := HttpClient.Get(url)
 	if err != nil {
-		err := fmt.Errorf("mev-blocks api request error: %s - %w", url, err)
-		return response, err
+		return response, wrapRequestError(err, url, "mev-blocks api request error")
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
 		err := fmt.Errorf("mev-blocks api response status code error: %s - %s", resp.Status, url)
 		return response, err
 	}
 
-	err = json.NewDecoder(resp.Body).Decode(&response)
+	err = decodeResponse(resp.Body, &response, url)
 	if err != nil {
-		err := fmt.Errorf("mev-blocks api response decode error: %s - %w", url, err)
 		return response, err
 	}
 
+	err = checkMissingFields(response.missingFields(), url)
+	if err != nil {
+		return response, err
+	}
+
+	if GetBlocksCacheTTL > 0 {
+		addBlocksResponseToCache(url, response)
+	}
+
 	return response, nil
 }
@@ -1,9 +1,7 @@
 package api
 
 import (
-	"encoding/json"
 	"fmt"
-	"net/http"
 	"strings"
 )
 
@@ -26,6 +24,14 @@ type FlashbotsTransaction struct {
 	TotalMinerReward string `json:"total_miner_reward"`
 }
 
+// checkFields adds the missing fields of the tx to fields (with the prefix of the enclosing object)
+func (tx FlashbotsTransaction) checkFields(fields *missingFieldList, prefix string) {
+	fields.check(tx.Hash == "", prefix+"transaction_hash")
+	fields.check(tx.BlockNumber == 0, prefix+"block_number")
+	fields.check(tx.EoaAddress == "", prefix+"eoa_address")
+	fields.check(tx.BundleType == "", prefix+"bundle_type")
+}
+
 type GetTransactionsOptions struct {
 	Before int64 // Filter transactions to before this block number (exclusive, does not include this block number). Default value: latest
 	Limit  int64 // Number of transactions that are returned
@@ -53,21 +59,36 @@ type TransactionsResponse struct {
 	Transactions      []FlashbotsTransaction `json:"transactions"`
 }
 
+// missingFields returns the names of the expected fields that are missing (or zero)
+func (r *TransactionsResponse) missingFields() (fields missingFieldList) {
+	fields.check(r.LatestBlockNumber == 0, "latest_block_number")
+	for _, tx := range r.Transactions {
+		tx.checkFields(&fields, "transactions.")
+	}
+	return fields
+}
+
 // GetTransactions returns the 100 most recent flashbots transactions. Use the before query param to
 // filter to transactions before a given block number.
 // https://blocks.flashbots.net/#api-Flashbots-GetV1Transactions
 func GetTransactions(options *GetTransactionsOptions) (response TransactionsResponse, err error) {
-	url := "https://blocks.flashbots.net/v1/transactions"
+	url := BaseUrl + "/v1/transactions"
 	if options != nil {
 		url = url + options.ToUriQuery()
 	}
 
-	resp, err := http.Get(url)
+	resp, err := HttpClient.Get(url)
+	if err != nil {
+		return response, wrapRequestError(err, url, "mev-blocks api request error")
+	}
+	defer resp.Body.Close()
+
+	err = decodeResponse(resp.Body, &response, url)
 	if err != nil {
 		return response, err
 	}
 
-	err = json.NewDecoder(resp.Body).Decode(&response)
+	err = checkMissingFields(response.missingFields(), url)
 	if err != nil {
 		return response, err
 	}
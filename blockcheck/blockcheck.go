@@ -4,13 +4,16 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"regexp"
 	"sort"
+	"strings"
 	"time"
 
 	ethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/metachris/flashbots/api"
 	"github.com/metachris/flashbots/common"
+	"github.com/metachris/flashbots/logging"
 	"github.com/metachris/go-ethutils/addresslookup"
 	"github.com/metachris/go-ethutils/blockswithtx"
 	"github.com/metachris/go-ethutils/utils"
@@ -59,8 +62,18 @@ type BlockCheck struct {
 	Bundles               []*common.Bundle
 
 	// Collection of errors
-	Errors   []string
-	FailedTx map[string]*FailedTx
+	Errors       []string
+	FailedTx     map[string]*FailedTx
+	SuccessfulTx map[string]*FailedTx // only with IncludeSuccessfulTx, and the tx with internal reverts (InternalCallTracer)
+
+	// The fee recipient of the block is in FlashbotsFeeRecipients: the Flashbots API wasn't queried, and all 0-gas tx are
+	// Flashbots tx if IsFlashbotsFeeRecipient
+	ClassifiedByFeeRecipient bool
+	IsFlashbotsFeeRecipient  bool
+
+	DuplicateFailedTx int // failed tx that were skipped because they were already recorded (with DeduplicateFailedTx)
+
+	detector *Detector // options of the failed tx detection (nil: the package variables)
 
 	// Helpers to filter later in user code
 	BiggestBundlePercentPriceDiff             float32 // on order error, max % difference to previous bundle
@@ -111,7 +124,7 @@ func CheckBlock(blockWithTx *blockswithtx.BlockWithTxReceipts, skipFlashbotsApi
 		ErrorCounter: ErrorCounts{},
 	}
 
-	err = check.QueryFlashbotsApi()
+	err = check.classifyFlashbots()
 	if err != nil {
 		return blockCheck, err
 	}
@@ -121,6 +134,18 @@ func CheckBlock(blockWithTx *blockswithtx.BlockWithTxReceipts, skipFlashbotsApi
 	return &check, nil
 }
 
+// classifyFlashbots classifies the block by its fee recipient (FlashbotsFeeRecipients), or else gets its Flashbots tx
+// from the Flashbots API
+func (b *BlockCheck) classifyFlashbots() error {
+	if isFlashbots, known := classifyByFeeRecipient(b.EthBlock); known {
+		b.ClassifiedByFeeRecipient = true
+		b.IsFlashbotsFeeRecipient = isFlashbots
+		b.FlashbotsApiBlock = &api.FlashbotsBlock{}
+		return nil
+	}
+	return b.QueryFlashbotsApi()
+}
+
 func (b *BlockCheck) AddError(msg string) {
 	b.Errors = append(b.Errors, msg)
 }
@@ -264,6 +289,29 @@ func (b *BlockCheck) CreateBundles() {
 	}
 }
 
+// FailedTxList returns the failed transactions in the order they appear in the block
+func (b *BlockCheck) FailedTxList() (ret []*FailedTx) {
+	for _, tx := range b.EthBlock.Transactions() {
+		if failedTx, found := b.FailedTx[tx.Hash().String()]; found {
+			ret = append(ret, failedTx)
+		}
+	}
+	return ret
+}
+
+// TxList returns the failed and successful (with IncludeSuccessfulTx) transactions in the order they appear in the
+// block
+func (b *BlockCheck) TxList() (ret []*FailedTx) {
+	for _, tx := range b.EthBlock.Transactions() {
+		if failedTx, found := b.FailedTx[tx.Hash().String()]; found {
+			ret = append(ret, failedTx)
+		} else if successfulTx, found := b.SuccessfulTx[tx.Hash().String()]; found {
+			ret = append(ret, successfulTx)
+		}
+	}
+	return ret
+}
+
 func (b *BlockCheck) IsFlashbotsTx(hash string) bool {
 	for _, tx := range b.FlashbotsTransactions {
 		if tx.Hash == hash {
@@ -336,7 +384,7 @@ func (b *BlockCheck) Check() {
 		}
 
 		if lowestGasPrice.Int64() == -1 || tx.GasPrice().Cmp(lowestGasPrice) == -1 {
-			if utils.IsBigIntZero(tx.GasPrice()) && len(tx.Data()) > 0 { // don't count Flashbots-like tx
+			if IsZeroGasTx(tx) { // don't count Flashbots-like tx
 				continue
 			}
 			lowestGasPrice = tx.GasPrice()
@@ -371,7 +419,7 @@ func (b *BlockCheck) Check() {
 			diffPercent2 := new(big.Float).Sub(big.NewFloat(1), diffPercent1)
 			diffPercent := new(big.Float).Mul(diffPercent2, big.NewFloat(100))
 
-			msg := fmt.Sprintf("bundle %d has %s%s lower effective-gas-price (%v) than [lowest non-fb transaction](<https://etherscan.io/tx/%s>) (%v)\n", bundle.Index, diffPercent.Text('f', 2), "%", common.BigIntToEString(bundle.RewardDivGasUsed, 4), lowestGasPriceTxHash, common.BigIntToEString(lowestGasPrice, 4))
+			msg := fmt.Sprintf("bundle %d has %s%s lower effective-gas-price (%v) than [lowest non-fb transaction](<%s>) (%v)\n", bundle.Index, diffPercent.Text('f', 2), "%", common.BigIntToEString(bundle.RewardDivGasUsed, 4), common.ExplorerTxUrl(lowestGasPriceTxHash), common.BigIntToEString(lowestGasPrice, 4))
 			b.AddError(msg)
 			b.ErrorCounter.BundleHasLowerFeeThanLowestNonFbTx += 1
 			b.BundleIsPayingLessThanLowestTxPercentDiff, _ = diffPercent.Float32()
@@ -382,9 +430,9 @@ func (b *BlockCheck) Check() {
 
 func (b *BlockCheck) SprintHeader(color bool, markdown bool) (msg string) {
 	minerAddr, found := AddressLookup.GetAddressDetail(b.Miner)
-	minerStr := fmt.Sprintf("[%s](<https://etherscan.io/address/%s>)", b.Miner, b.Miner)
+	minerStr := fmt.Sprintf("[%s](<%s>)", b.Miner, common.ExplorerAddressUrl(b.Miner))
 	if found {
-		minerStr = fmt.Sprintf("[%s](<https://etherscan.io/address/%s>)", minerAddr.Name, b.Miner)
+		minerStr = fmt.Sprintf("[%s](<%s>)", minerAddr.Name, common.ExplorerAddressUrl(b.Miner))
 	}
 
 	numTx := len(b.BlockWithTxReceipts.Block.Transactions())
@@ -392,13 +440,20 @@ func (b *BlockCheck) SprintHeader(color bool, markdown bool) (msg string) {
 	numBundles := len(b.Bundles)
 
 	if markdown {
-		msg = fmt.Sprintf("Block [%d](<https://etherscan.io/block/%d>) ([bundle explorer](<https://flashbots-explorer.marto.lol/?block=%d>)), miner: %s - tx: %d, fb-tx: %d, bundles: %d", b.Number, b.Number, b.Number, minerStr, numTx, numFbTx, numBundles)
+		msg = fmt.Sprintf("Block [%d](<%s>) ([bundle explorer](<https://flashbots-explorer.marto.lol/?block=%d>)), miner: %s - tx: %d, fb-tx: %d, bundles: %d", b.Number, common.ExplorerBlockUrl(b.Number), b.Number, minerStr, numTx, numFbTx, numBundles)
 	} else {
-		msg = fmt.Sprintf("Block %d, miner %s - tx: %d, fb-tx: %d, bundles: %d", b.Number, minerStr, numTx, numFbTx, numBundles)
+		msg = fmt.Sprintf("Block %d (%s), miner %s - tx: %d, fb-tx: %d, bundles: %d", b.Number, common.ExplorerBlockUrl(b.Number), plainLinks(minerStr), numTx, numFbTx, numBundles)
 	}
 	return msg
 }
 
+var markdownLinkRegexp = regexp.MustCompile(`\[([^\]]*)\]\(<([^>]*)>\)`)
+
+// plainLinks replaces the markdown links "[text](<url>)" of msg with "text (url)", which terminals make clickable
+func plainLinks(msg string) string {
+	return markdownLinkRegexp.ReplaceAllString(msg, "$1 ($2)")
+}
+
 func (b *BlockCheck) Sprint(color bool, markdown bool, includeBundles bool) (msg string) {
 	msg = b.SprintHeader(color, markdown)
 	msg += "\n"
@@ -406,7 +461,12 @@ func (b *BlockCheck) Sprint(color bool, markdown bool, includeBundles bool) (msg
 	// Print errors
 	for _, err := range b.Errors {
 		err = "- error: " + err
-		if color {
+		if !markdown {
+			err = plainLinks(err)
+		}
+		if color && strings.Contains(err, repeatedSenderMarker) {
+			msg += fmt.Sprintf(utils.ErrorColor, err)
+		} else if color {
 			msg += fmt.Sprintf(utils.WarningColor, err)
 		} else {
 			msg += err
@@ -445,8 +505,30 @@ func (b *BlockCheck) Sprint(color bool, markdown bool, includeBundles bool) (msg
 	return msg
 }
 
+// pendingFailedTx is a failed tx that is recorded after its revert reason was fetched
+type pendingFailedTx struct {
+	failedTx       *FailedTx
+	tx             *types.Transaction // nil if not found in the block
+	from           ethcommon.Address
+	bundleIndex    int64                    // of the Flashbots API (only for Flashbots tx)
+	byFeeRecipient bool                     // Flashbots tx classified by the fee recipient of the block (without bundle index)
+	record         func(failedTx *FailedTx) // adds the error message and counters
+}
+
 func (b *BlockCheck) checkBlockForFailedTx() (failedTransactions []FailedTx) {
+	d := b.detector
+	if d == nil {
+		d = packageDetector()
+	}
 	b.FailedTx = make(map[string]*FailedTx)
+	b.SuccessfulTx = make(map[string]*FailedTx)
+	pending := make([]*pendingFailedTx, 0)
+
+	// Flashbots tx hashes of this block, to classify the tx of the block without going through the list every time
+	flashbotsTxHashes := make(map[string]bool, len(b.FlashbotsTransactions))
+	for _, fbTx := range b.FlashbotsTransactions {
+		flashbotsTxHashes[fbTx.Hash] = true
+	}
 
 	// 1. iterate over all Flashbots transactions and check if any has failed
 	for _, fbTx := range b.FlashbotsTransactions {
@@ -455,61 +537,139 @@ func (b *BlockCheck) checkBlockForFailedTx() (failedTransactions []FailedTx) {
 			continue
 		}
 
+		if receipt.Status == 1 && d.IncludeSuccessfulTx {
+			successfulTx := b.newFailedTx(b.EthBlock.Transaction(ethcommon.HexToHash(fbTx.Hash)), receipt, TxStatusSuccess, true, fbTx.EoaAddress, fbTx.ToAddress)
+			if d.isFailedTxIncluded(successfulTx) {
+				b.SuccessfulTx[successfulTx.Hash] = successfulTx
+			}
+		}
+
 		if receipt.Status == 0 { // failed Flashbots TX
-			b.FailedTx[fbTx.Hash] = &FailedTx{
-				Hash:        fbTx.Hash,
-				IsFlashbots: true,
-				From:        fbTx.EoaAddress,
-				To:          fbTx.ToAddress,
-				Block:       uint64(fbTx.BlockNumber),
+			tx := b.EthBlock.Transaction(ethcommon.HexToHash(fbTx.Hash))
+			failedTx := b.newFailedTx(tx, receipt, TxStatusFailed, true, fbTx.EoaAddress, fbTx.ToAddress)
+			if !d.isFailedTxIncluded(failedTx) {
+				continue
+			}
+			if d.isDuplicateFailedTx(failedTx) {
+				b.DuplicateFailedTx += 1
+				continue
 			}
 
-			msg := fmt.Sprintf("failed %s tx [%s](<https://etherscan.io/tx/%s>) in bundle %d (from [%s](<https://etherscan.io/address/%s>))\n", fbTx.BundleType, fbTx.Hash, fbTx.Hash, fbTx.BundleIndex, fbTx.EoaAddress, fbTx.EoaAddress)
-			b.ErrorCounter.FailedFlashbotsTx += 1
-			b.AddError(msg)
-			b.HasFailedFlashbotsTx = true
-			if fbTx.BundleType == api.BundleTypeFlashbots { // alert only for type=flashbots
-				b.TriggerAlertOnFailedTx = true
+			fbTx := fbTx
+			pending = append(pending, &pendingFailedTx{failedTx: failedTx, tx: tx, from: ethcommon.HexToAddress(fbTx.EoaAddress), bundleIndex: fbTx.BundleIndex, record: func(failedTx *FailedTx) {
+				msg := fmt.Sprintf("failed %s tx [%s](<%s>) in bundle %d (from [%s](<%s>)) - gas used: %d%s%s%s\n", fbTx.BundleType, fbTx.Hash, common.ExplorerTxUrl(fbTx.Hash), fbTx.BundleIndex, fbTx.EoaAddress, common.ExplorerAddressUrl(fbTx.EoaAddress), failedTx.GasUsed, revertReasonMsg(failedTx), d.repeatedSenderMsg(failedTx), bundleMsg(failedTx))
+				b.ErrorCounter.FailedFlashbotsTx += 1
+				b.AddError(msg)
+				b.HasFailedFlashbotsTx = true
+				if fbTx.BundleType == api.BundleTypeFlashbots { // alert only for type=flashbots
+					b.TriggerAlertOnFailedTx = true
+				}
+			}})
+		}
+	}
+
+	// 2. iterate over all failed 0-gas transactions in the EthBlock. checkTx returns the decision (for LogTxDecisions).
+	checkTx := func(tx *types.Transaction, receipt *types.Receipt) (decision string) {
+		if !IsZeroGasTx(tx) {
+			return "skipped: not a 0-gas tx with data"
+		}
+
+		isFlashbotsApiTx := flashbotsTxHashes[tx.Hash().String()] // already handled in 1.
+		isFlashbotsTx := isFlashbotsApiTx || b.IsFlashbotsFeeRecipient
+		if d.OnlyFlashbotsTx && !isFlashbotsTx {
+			return "skipped: not a Flashbots tx (only Flashbots tx)"
+		}
+		if receipt.Status == 1 {
+			if InternalCallTracer != nil {
+				if call := getInternalRevert(tx.Hash()); call != nil {
+					return b.recordInternalRevert(d, tx, receipt, isFlashbotsTx, call)
+				}
+			}
+			if isFlashbotsApiTx {
+				return "skipped: successful Flashbots tx (checked with the Flashbots API data)"
 			}
+			if !d.IncludeSuccessfulTx {
+				return "skipped: successful"
+			}
+
+			from, to := txAddresses(tx)
+			successfulTx := b.newFailedTx(tx, receipt, TxStatusSuccess, isFlashbotsTx, from.String(), to)
+			if !d.isFailedTxIncluded(successfulTx) {
+				return "skipped: successful, filtered out"
+			}
+			b.SuccessfulTx[successfulTx.Hash] = successfulTx
+			return "recorded: successful 0-gas tx"
+		}
+
+		// failed tx
+		if isFlashbotsApiTx {
+			// Already handled (Flashbots TX)
+			return "failed Flashbots tx (checked with the Flashbots API data)"
 		}
+
+		from, to := txAddresses(tx)
+		failedTx := b.newFailedTx(tx, receipt, TxStatusFailed, isFlashbotsTx, from.String(), to)
+		if !d.isFailedTxIncluded(failedTx) {
+			return "skipped: failed, filtered out (min value, min gas used or address filter)"
+		}
+		if d.isDuplicateFailedTx(failedTx) {
+			b.DuplicateFailedTx += 1
+			return "skipped: failed, already recorded"
+		}
+
+		if isFlashbotsTx { // Flashbots block by the fee recipient, without bundle info
+			pending = append(pending, &pendingFailedTx{failedTx: failedTx, tx: tx, from: from, byFeeRecipient: true, record: func(failedTx *FailedTx) {
+				msg := fmt.Sprintf("failed Flashbots tx [%s](<%s>) (by the block's fee recipient) from [%s](<%s>) - gas used: %d%s%s%s\n", failedTx.Hash, common.ExplorerTxUrl(failedTx.Hash), failedTx.From, common.ExplorerAddressUrl(failedTx.From), failedTx.GasUsed, revertReasonMsg(failedTx), d.repeatedSenderMsg(failedTx), bundleMsg(failedTx))
+				b.ErrorCounter.FailedFlashbotsTx += 1
+				b.AddError(msg)
+				b.HasFailedFlashbotsTx = true
+				b.TriggerAlertOnFailedTx = true
+			}})
+			return "recorded: failed Flashbots tx (by the fee recipient of the block)"
+		}
+
+		pending = append(pending, &pendingFailedTx{failedTx: failedTx, tx: tx, from: from, record: func(failedTx *FailedTx) {
+			msg := fmt.Sprintf("failed 0-gas tx [%s](<%s>) from [%s](<%s>) - gas used: %d%s%s%s\n", failedTx.Hash, common.ExplorerTxUrl(failedTx.Hash), failedTx.From, common.ExplorerAddressUrl(failedTx.From), failedTx.GasUsed, revertReasonMsg(failedTx), d.repeatedSenderMsg(failedTx), bundleMsg(failedTx))
+			b.AddError(msg)
+			b.ErrorCounter.Failed0GasTx += 1
+			b.HasFailed0GasTx = true
+			b.TriggerAlertOnFailedTx = true
+		}})
+		return "recorded: failed 0-gas tx"
 	}
 
-	// 2. iterate over all failed 0-gas transactions in the EthBlock
 	for _, tx := range b.EthBlock.Transactions() {
 		receipt := b.BlockWithTxReceipts.TxReceipts[tx.Hash()]
 		if receipt == nil {
 			continue
 		}
 
-		if utils.IsBigIntZero(tx.GasPrice()) && len(tx.Data()) > 0 {
-			if receipt.Status == 0 { // failed tx
-				if _, exists := b.FailedTx[tx.Hash().String()]; exists {
-					// Already known (Flashbots TX)
-					continue
-				}
+		decision := checkTx(tx, receipt)
+		if LogTxDecisions {
+			logging.Log.Infow("Tx decision", "block", b.Number, "hash", tx.Hash().String(), "zeroGasPrice", isZeroGasPrice(tx), "hasData", len(tx.Data()) > 0, "status", receipt.Status, "decision", decision)
+		}
+	}
 
-				from, _ := utils.GetTxSender(tx)
-				to := ""
-				if tx.To() != nil {
-					to = tx.To().String()
-				}
-				b.FailedTx[tx.Hash().String()] = &FailedTx{
-					Hash:        tx.Hash().String(),
-					IsFlashbots: false,
-					From:        from.String(),
-					To:          to,
-					Block:       uint64(b.Number),
-				}
+	// 3. group the likely failed bundles, get the revert reasons concurrently (one eth_call each) and the Etherscan
+	// contract info, then record the failed tx in the original order
+	groupFailedBundles(pending, b.Number, b.EthBlock.Coinbase().Hex())
 
-				msg := fmt.Sprintf("failed 0-gas tx [%s](<https://etherscan.io/tx/%s>) from [%s](<https://etherscan.io/address/%s>)\n", tx.Hash(), tx.Hash(), from, from)
-				b.AddError(msg)
-				b.ErrorCounter.Failed0GasTx += 1
-				b.HasFailed0GasTx = true
-				b.TriggerAlertOnFailedTx = true
-			}
+	if RevertReasonClient != nil {
+		getRevertReasons(pending, b.Number)
+	}
+
+	if EtherscanApiKey != "" {
+		for _, p := range pending {
+			setEtherscanInfo(p.failedTx, p.tx)
 		}
 	}
 
+	for _, p := range pending {
+		d.countSenderFailure(p.failedTx)
+		b.FailedTx[p.failedTx.Hash] = p.failedTx
+		p.record(p.failedTx)
+	}
+
 	return failedTransactions
 }
 
@@ -0,0 +1,61 @@
+// Identify the builder of a block by its fee recipient (coinbase) or extra-data
+package blockcheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// BuilderFeeRecipients maps known builder fee recipient addresses (lowercase) to the builder name
+var BuilderFeeRecipients map[string]string = map[string]string{
+	"0xdafea492d9c6733ae3d56b7ed1adb60692c98bc5": "flashbots",
+	"0x95222290dd7278aa3ddd389cc1e1d165cc4bafe5": "beaverbuild",
+	"0x1f9090aae28b8a3dceadf281b0f12828e676c326": "rsync-builder",
+	"0x690b9a9e9aa1c9db991c7721a92d351db4fac990": "builder0x69",
+	"0x4838b106fce9647bdf1e7877bf73ce8b0bad5f97": "titan",
+}
+
+// LoadBuilderFeeRecipients replaces the builder mapping with the one from a JSON file ({"address": "name", ...}). If an
+// address isn't a valid 20-byte hex address, it fails with an error naming it and keeps the current mapping.
+func LoadBuilderFeeRecipients(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	builders := make(map[string]string)
+	err = json.Unmarshal(data, &builders)
+	if err != nil {
+		return err
+	}
+
+	feeRecipients := make(map[string]string)
+	for address, name := range builders {
+		if !ethcommon.IsHexAddress(address) {
+			return fmt.Errorf("invalid fee recipient address in %s: %s", path, address)
+		}
+		feeRecipients[strings.ToLower(ethcommon.HexToAddress(address).Hex())] = name
+	}
+	BuilderFeeRecipients = feeRecipients
+	return nil
+}
+
+// GetBuilderName returns the builder name from the fee recipient, or else the printable part of the block's extra-data
+func GetBuilderName(block *types.Block) string {
+	if name, found := BuilderFeeRecipients[strings.ToLower(block.Coinbase().Hex())]; found {
+		return name
+	}
+
+	name := strings.Map(func(r rune) rune {
+		if r < 32 || r > 126 {
+			return -1
+		}
+		return r
+	}, string(block.Extra()))
+	return strings.TrimSpace(name)
+}
@@ -0,0 +1,54 @@
+package blockcheck
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestGetBuilderName(t *testing.T) {
+	header := &types.Header{Number: big.NewInt(1), Coinbase: ethcommon.HexToAddress("0x95222290DD7278Aa3Ddd389Cc1E1d165CC4BAfe5")}
+	if name := GetBuilderName(types.NewBlockWithHeader(header)); name != "beaverbuild" {
+		t.Error("Wrong builder name:", name, "wanted:", "beaverbuild")
+	}
+
+	header = &types.Header{Number: big.NewInt(1), Extra: []byte("\x00some builder\x01")}
+	if name := GetBuilderName(types.NewBlockWithHeader(header)); name != "some builder" {
+		t.Error("Wrong builder name from extra-data:", name, "wanted:", "some builder")
+	}
+}
+
+func TestLoadBuilderFeeRecipients(t *testing.T) {
+	defaultFeeRecipients := BuilderFeeRecipients
+	defer func() { BuilderFeeRecipients = defaultFeeRecipients }()
+
+	dir, err := ioutil.TempDir("", "builders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "builders.json")
+	ioutil.WriteFile(path, []byte(`{"0x95222290DD7278Aa3Ddd389Cc1E1d165CC4BAfe5": "beaver", "0x123": "invalid"}`), 0644)
+	err = LoadBuilderFeeRecipients(path)
+	if err == nil || !strings.Contains(err.Error(), "0x123") {
+		t.Error("Expected an error naming the invalid address:", err)
+	}
+	if BuilderFeeRecipients["0x95222290dd7278aa3ddd389cc1e1d165cc4bafe5"] != "beaverbuild" {
+		t.Error("The current mapping should be kept on errors")
+	}
+
+	ioutil.WriteFile(path, []byte(`{"0x95222290DD7278Aa3Ddd389Cc1E1d165CC4BAfe5": "beaver"}`), 0644)
+	if err = LoadBuilderFeeRecipients(path); err != nil {
+		t.Fatal(err)
+	}
+	if BuilderFeeRecipients["0x95222290dd7278aa3ddd389cc1e1d165cc4bafe5"] != "beaver" {
+		t.Error("Wrong builder name:", BuilderFeeRecipients["0x95222290dd7278aa3ddd389cc1e1d165cc4bafe5"], "wanted:", "beaver")
+	}
+}
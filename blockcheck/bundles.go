@@ -0,0 +1,69 @@
+// Grouping the failed tx of a block that are likely one failed bundle
+package blockcheck
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// groupFailedBundles sets BundleID and BundleSize of the pending failed tx that are likely one failed bundle: Flashbots
+// tx with the same bundle index, and other 0-gas tx (and Flashbots tx classified by the fee recipient, which have no
+// bundle index) at adjacent positions in the block that share the sender or pay the coinbase. Groups of a single tx are
+// not marked. The ids are "<block>-<n>", numbered by position.
+func groupFailedBundles(pending []*pendingFailedTx, blockNumber int64, coinbase string) {
+	sorted := make([]*pendingFailedTx, len(pending))
+	copy(sorted, pending)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].failedTx.TxIndex < sorted[j].failedTx.TxIndex
+	})
+
+	groups := make([][]*FailedTx, 0)
+	flashbotsGroups := make(map[int64]int) // bundle index -> index in groups
+	for i, p := range sorted {
+		if p.hasBundleIndex() {
+			if g, found := flashbotsGroups[p.bundleIndex]; found {
+				groups[g] = append(groups[g], p.failedTx)
+				continue
+			}
+			flashbotsGroups[p.bundleIndex] = len(groups)
+		} else if i > 0 && !sorted[i-1].hasBundleIndex() && isSameBundle(sorted[i-1].failedTx, p.failedTx, coinbase) {
+			groups[len(groups)-1] = append(groups[len(groups)-1], p.failedTx)
+			continue
+		}
+		groups = append(groups, []*FailedTx{p.failedTx})
+	}
+
+	n := 0
+	for _, group := range groups {
+		if len(group) < 2 {
+			continue
+		}
+		n += 1
+		for _, failedTx := range group {
+			failedTx.BundleID = fmt.Sprintf("%d-%d", blockNumber, n)
+			failedTx.BundleSize = len(group)
+		}
+	}
+}
+
+// hasBundleIndex returns true for Flashbots tx of the Flashbots API, which are grouped by their bundle index
+func (p *pendingFailedTx) hasBundleIndex() bool {
+	return p.failedTx.IsFlashbots && !p.byFeeRecipient
+}
+
+// isSameBundle returns true if the 0-gas tx cur directly follows prev, and has the same sender or one of them pays the
+// coinbase
+func isSameBundle(prev *FailedTx, cur *FailedTx, coinbase string) bool {
+	if cur.TxIndex != prev.TxIndex+1 {
+		return false
+	}
+	return strings.EqualFold(prev.From, cur.From) || strings.EqualFold(prev.To, coinbase) || strings.EqualFold(cur.To, coinbase)
+}
+
+func bundleMsg(failedTx *FailedTx) string {
+	if failedTx.BundleID == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (%d txs in failed bundle %s)", failedTx.BundleSize, failedTx.BundleID)
+}
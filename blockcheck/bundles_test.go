@@ -0,0 +1,71 @@
+package blockcheck
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestCheckBlockForFailedTxBundles(t *testing.T) {
+	keyA, _ := crypto.GenerateKey()
+	keyB, _ := crypto.GenerateKey()
+	keyC, _ := crypto.GenerateKey()
+	coinbase := ethcommon.Address{} // of the test block
+	payCoinbaseTx := signTestTx(t, keyC, &types.LegacyTx{GasPrice: big.NewInt(0), Gas: 100_000, To: &coinbase, Data: testTxData})
+
+	txs := []*types.Transaction{
+		newLegacyTestTx(t, keyA, 0, 0), // bundle 1
+		newLegacyTestTx(t, keyA, 1, 0), // bundle 1 (same sender)
+		newLegacyTestTx(t, keyB, 0, 0), // other sender
+		newLegacyTestTx(t, keyA, 2, 0), // successful
+		newLegacyTestTx(t, keyB, 1, 0), // bundle 2
+		payCoinbaseTx,                  // bundle 2 (pays the coinbase)
+	}
+	check := newTestBlockCheck(txs, []uint64{0, 0, 0, 1, 0, 0})
+	check.checkBlockForFailedTx()
+
+	expected := []struct {
+		bundleId   string
+		bundleSize int
+	}{{"13000000-1", 2}, {"13000000-1", 2}, {"", 0}, {"", 0}, {"13000000-2", 2}, {"13000000-2", 2}}
+	for i, tx := range txs {
+		failedTx, found := check.FailedTx[tx.Hash().String()]
+		if !found {
+			continue
+		}
+		if failedTx.BundleID != expected[i].bundleId {
+			t.Error("Wrong BundleID of tx", i, failedTx.BundleID, "wanted:", expected[i].bundleId)
+		}
+		if failedTx.BundleSize != expected[i].bundleSize {
+			t.Error("Wrong BundleSize of tx", i, failedTx.BundleSize, "wanted:", expected[i].bundleSize)
+		}
+	}
+
+	if !strings.Contains(check.Errors[0], "(2 txs in failed bundle 13000000-1)") {
+		t.Error("Error should mention the bundle:", check.Errors[0])
+	}
+	if strings.Contains(check.Errors[2], "failed bundle") {
+		t.Error("Error should not mention a bundle:", check.Errors[2])
+	}
+}
+
+func TestGroupFailedBundlesFlashbots(t *testing.T) {
+	pending := []*pendingFailedTx{
+		{failedTx: &FailedTx{IsFlashbots: true, From: "0xa", TxIndex: 0}, bundleIndex: 0},
+		{failedTx: &FailedTx{IsFlashbots: true, From: "0xb", TxIndex: 3}, bundleIndex: 1},
+		{failedTx: &FailedTx{IsFlashbots: true, From: "0xa", TxIndex: 2}, bundleIndex: 0},
+		{failedTx: &FailedTx{From: "0xa", TxIndex: 1}}, // not a Flashbots tx, not grouped with the Flashbots tx before it
+	}
+	groupFailedBundles(pending, 5, "0xc")
+
+	expected := []string{"5-1", "", "5-1", ""}
+	for i, p := range pending {
+		if p.failedTx.BundleID != expected[i] {
+			t.Error("Wrong BundleID of tx", i, p.failedTx.BundleID, "wanted:", expected[i])
+		}
+	}
+}
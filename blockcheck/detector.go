@@ -0,0 +1,82 @@
+// Detector for failed Flashbots and 0-gas transactions, for use as a library
+package blockcheck
+
+import (
+	"math/big"
+
+	"github.com/metachris/flashbots/api"
+	"github.com/metachris/go-ethutils/blockswithtx"
+)
+
+// Detector finds the failed Flashbots and other 0-gas transactions of a block. Unlike CheckBlock it doesn't look at
+// the bundles and doesn't need the miner address lookup. Its options are fields, the package variables of the same
+// name (IncludeSuccessfulTx, FailedTxFilter, ...) only apply to CheckBlock. FlashbotsFeeRecipients, RevertReasonClient,
+// InternalCallTracer and EtherscanApiKey apply to both. The zero value is ready to use, a Detector is safe for concurrent
+// use but must not be copied after the first Detect.
+type Detector struct {
+	// SkipFlashbotsApi doesn't query the Flashbots API for blocks that are not in FlashbotsBlockCache (failed tx are
+	// then only detected as 0-gas tx)
+	SkipFlashbotsApi bool
+
+	IncludeSuccessfulTx     bool                          // see the package variable IncludeSuccessfulTx
+	OnlyFlashbotsTx         bool                          // see OnlyFlashbotsTx
+	Filter                  func(failedTx *FailedTx) bool // see FailedTxFilter
+	MinValue                *big.Int                      // see FailedTxMinValue
+	MinGasUsed              uint64                        // see FailedTxMinGasUsed
+	Deduplicate             bool                          // see DeduplicateFailedTx (per Detector)
+	RepeatedSenderThreshold int                           // see RepeatedSenderThreshold (per Detector)
+
+	state       failedTxState  // run state of this Detector
+	sharedState *failedTxState // run state of CheckBlock instead (see packageDetector)
+}
+
+func NewDetector() *Detector {
+	return &Detector{}
+}
+
+// runState returns the run state with the seen failed tx and the failures per sender
+func (d *Detector) runState() *failedTxState {
+	if d.sharedState != nil {
+		return d.sharedState
+	}
+	return &d.state
+}
+
+// packageDetector returns a Detector with the options of the package variables and the run state of CheckBlock
+func packageDetector() *Detector {
+	return &Detector{
+		IncludeSuccessfulTx:     IncludeSuccessfulTx,
+		OnlyFlashbotsTx:         OnlyFlashbotsTx,
+		Filter:                  FailedTxFilter,
+		MinValue:                FailedTxMinValue,
+		MinGasUsed:              FailedTxMinGasUsed,
+		Deduplicate:             DeduplicateFailedTx,
+		RepeatedSenderThreshold: RepeatedSenderThreshold,
+
+		sharedState: &packageFailedTxState,
+	}
+}
+
+// Detect returns the failed transactions of the block, in block order. Like CheckBlock, blocks with a fee recipient in
+// FlashbotsFeeRecipients are classified by it, without the Flashbots API.
+func (d *Detector) Detect(block *blockswithtx.BlockWithTxReceipts) (failedTxs []FailedTx, err error) {
+	check := BlockCheck{
+		BlockWithTxReceipts:   block,
+		EthBlock:              block.Block,
+		FlashbotsTransactions: make([]api.FlashbotsTransaction, 0),
+		SkipFlashbotsApi:      d.SkipFlashbotsApi,
+		Number:                block.Block.Number().Int64(),
+		detector:              d,
+	}
+
+	err = check.classifyFlashbots()
+	if err != nil {
+		return failedTxs, err
+	}
+
+	check.checkBlockForFailedTx()
+	for _, failedTx := range check.FailedTxList() {
+		failedTxs = append(failedTxs, *failedTx)
+	}
+	return failedTxs, nil
+}
@@ -0,0 +1,139 @@
+package blockcheck
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/metachris/flashbots/api"
+)
+
+func TestDetector(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+
+	failedFlashbotsTx := newLegacyTestTx(t, key, 0, 0)
+	successfulTx := newLegacyTestTx(t, key, 1, 0)
+	failed0GasTx := newDynamicFeeTestTx(t, key, 2, 0)
+	failedTxWithGasPrice := newLegacyTestTx(t, key, 3, 50e9)
+
+	txs := []*types.Transaction{failedFlashbotsTx, successfulTx, failed0GasTx, failedTxWithGasPrice}
+	block := newTestBlockCheck(txs, []uint64{0, 1, 0, 0}).BlockWithTxReceipts
+
+	// Without Flashbots API data, both failed 0-gas tx are detected as non-Flashbots
+	detector := NewDetector()
+	detector.SkipFlashbotsApi = true
+	failedTxs, err := detector.Detect(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failedTxs) != 2 {
+		t.Fatal("Wrong number of failed tx:", len(failedTxs), "wanted:", 2)
+	}
+	for _, failedTx := range failedTxs {
+		if failedTx.IsFlashbots {
+			t.Error("Should not be a Flashbots tx:", failedTx.Hash)
+		}
+	}
+
+	// With the Flashbots block data, the first tx is a Flashbots tx. The result is in block order.
+	FlashbotsBlockCache[block.Block.Number().Int64()] = api.FlashbotsBlock{
+		BlockNumber: block.Block.Number().Int64(),
+		Transactions: []api.FlashbotsTransaction{
+			{Hash: failedFlashbotsTx.Hash().String(), EoaAddress: sender.String(), ToAddress: testToAddress.String(), BlockNumber: block.Block.Number().Int64()},
+		},
+	}
+	defer delete(FlashbotsBlockCache, block.Block.Number().Int64())
+
+	failedTxs, err = detector.Detect(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failedTxs) != 2 {
+		t.Fatal("Wrong number of failed tx:", len(failedTxs), "wanted:", 2)
+	}
+	if failedTxs[0].Hash != failedFlashbotsTx.Hash().String() || !failedTxs[0].IsFlashbots {
+		t.Error("Wrong first failed tx:", failedTxs[0], "wanted Flashbots tx:", failedFlashbotsTx.Hash())
+	}
+	if failedTxs[1].Hash != failed0GasTx.Hash().String() || failedTxs[1].IsFlashbots {
+		t.Error("Wrong second failed tx:", failedTxs[1], "wanted 0-gas tx:", failed0GasTx.Hash())
+	}
+	if failedTxs[1].From != sender.String() {
+		t.Error("Wrong sender:", failedTxs[1].From, "wanted:", sender)
+	}
+}
+
+func TestDetectorOptions(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	failedTx := newLegacyTestTx(t, key, 0, 0)
+	successfulTx := newLegacyTestTx(t, key, 1, 0)
+	block := newTestBlockCheck([]*types.Transaction{failedTx, successfulTx}, []uint64{0, 1}).BlockWithTxReceipts
+
+	// The package variables don't apply to a Detector
+	IncludeSuccessfulTx = true
+	defer func() { IncludeSuccessfulTx = false }()
+
+	detector := NewDetector()
+	detector.SkipFlashbotsApi = true
+	detector.Deduplicate = true
+	failedTxs, err := detector.Detect(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failedTxs) != 1 || failedTxs[0].Hash != failedTx.Hash().String() {
+		t.Fatal("Wrong failed tx:", failedTxs, "wanted:", failedTx.Hash())
+	}
+
+	// Deduplicated per Detector
+	failedTxs, _ = detector.Detect(block)
+	if len(failedTxs) != 0 {
+		t.Error("Wrong number of failed tx after deduplication:", len(failedTxs), "wanted:", 0)
+	}
+	other := NewDetector()
+	other.SkipFlashbotsApi = true
+	other.IncludeSuccessfulTx = true
+	other.Filter = func(failedTx *FailedTx) bool { return failedTx.Nonce == 0 }
+	tx, _ := other.Detect(block)
+	if len(tx) != 1 || tx[0].Hash != failedTx.Hash().String() {
+		t.Error("Wrong failed tx of other detector:", tx, "wanted:", failedTx.Hash())
+	}
+}
+
+func TestDetectorZeroValue(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	failedTx := newLegacyTestTx(t, key, 0, 0)
+	block := newTestBlockCheck([]*types.Transaction{failedTx}, []uint64{0}).BlockWithTxReceipts
+
+	// A Detector literal, without NewDetector
+	detector := &Detector{SkipFlashbotsApi: true, Deduplicate: true, RepeatedSenderThreshold: 1}
+	failedTxs, err := detector.Detect(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failedTxs) != 1 || failedTxs[0].SenderFailures != 1 {
+		t.Fatal("Wrong failed tx:", failedTxs, "wanted one with SenderFailures 1")
+	}
+	failedTxs, _ = detector.Detect(block)
+	if len(failedTxs) != 0 {
+		t.Error("Wrong number of failed tx after deduplication:", len(failedTxs), "wanted:", 0)
+	}
+}
+
+func TestDetectorByFeeRecipient(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	failedTx := newLegacyTestTx(t, key, 0, 0)
+	block := newTestBlockCheck([]*types.Transaction{failedTx}, []uint64{0}).BlockWithTxReceipts
+
+	FlashbotsFeeRecipients = map[string]bool{strings.ToLower(block.Block.Coinbase().Hex()): true}
+	defer func() { FlashbotsFeeRecipients = nil }()
+
+	// Classified by the fee recipient, without the Flashbots API
+	failedTxs, err := NewDetector().Detect(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failedTxs) != 1 || !failedTxs[0].IsFlashbots {
+		t.Error("Wrong failed tx:", failedTxs, "wanted a Flashbots tx by the fee recipient")
+	}
+}
@@ -0,0 +1,111 @@
+// Contract name and method of failed transactions via the Etherscan API
+// https://docs.etherscan.io/api-endpoints/contracts#get-contract-source-code-for-verified-contract-source-codes
+package blockcheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/metachris/flashbots/common"
+	"github.com/metachris/flashbots/logging"
+)
+
+// EtherscanApiKey enables setting Method and ContractName of failed tx (one Etherscan request per contract, empty
+// disables it)
+var EtherscanApiKey string
+
+var etherscanClient = &http.Client{Timeout: 10 * time.Second}
+
+type etherscanContract struct {
+	Name string
+	Abi  *abi.ABI // nil if the contract is not verified
+}
+
+// Contracts by lowercase address. Unverified contracts are cached too, failed requests are not.
+var etherscanContractCache = make(map[string]*etherscanContract)
+var etherscanContractCacheLock sync.Mutex
+
+type etherscanResponse struct {
+	Status  string          `json:"status"`
+	Message string          `json:"message"`
+	Result  json.RawMessage `json:"result"` // error message instead of the result if status is not "1"
+}
+
+type etherscanSourceCode struct {
+	ContractName string
+	ABI          string
+}
+
+// getEtherscanContract returns the name and ABI of the contract at address
+func getEtherscanContract(address string) (*etherscanContract, error) {
+	address = strings.ToLower(address)
+	etherscanContractCacheLock.Lock()
+	contract, found := etherscanContractCache[address]
+	etherscanContractCacheLock.Unlock()
+	if found {
+		return contract, nil
+	}
+
+	query := url.Values{"module": {"contract"}, "action": {"getsourcecode"}, "address": {address}, "apikey": {EtherscanApiKey}}
+	res, err := etherscanClient.Get(common.EtherscanApiUrl + "/api?" + query.Encode())
+	if err != nil {
+		if urlErr, ok := err.(*url.Error); ok { // don't return the URL, it contains the api key
+			return nil, fmt.Errorf("etherscan request error: %w", urlErr.Err)
+		}
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		return nil, fmt.Errorf("etherscan response status: %s", res.Status)
+	}
+
+	var response etherscanResponse
+	err = json.NewDecoder(res.Body).Decode(&response)
+	if err != nil {
+		return nil, err
+	}
+
+	var sourceCodes []etherscanSourceCode
+	if response.Status != "1" || json.Unmarshal(response.Result, &sourceCodes) != nil || len(sourceCodes) == 0 {
+		return nil, fmt.Errorf("etherscan error: %s %s", response.Message, string(response.Result))
+	}
+
+	contract = &etherscanContract{Name: sourceCodes[0].ContractName}
+	if contractAbi, err := abi.JSON(strings.NewReader(sourceCodes[0].ABI)); err == nil { // not JSON if not verified
+		contract.Abi = &contractAbi
+	}
+
+	etherscanContractCacheLock.Lock()
+	etherscanContractCache[address] = contract
+	etherscanContractCacheLock.Unlock()
+	return contract, nil
+}
+
+// setEtherscanInfo sets ContractName and Method of the failed tx. They stay empty if the contract is not verified or
+// the Etherscan API returns an error.
+func setEtherscanInfo(failedTx *FailedTx, tx *types.Transaction) {
+	if tx == nil || tx.To() == nil || common.EtherscanApiUrl == "" {
+		return
+	}
+
+	contract, err := getEtherscanContract(tx.To().Hex())
+	if err != nil {
+		logging.Log.Debugw("Etherscan error", "hash", failedTx.Hash, "to", tx.To().Hex(), "error", err)
+		return
+	}
+
+	failedTx.ContractName = contract.Name
+	if contract.Abi != nil && len(tx.Data()) >= 4 {
+		if method, err := contract.Abi.MethodById(tx.Data()[:4]); err == nil {
+			failedTx.Method = method.Sig
+		}
+	}
+}
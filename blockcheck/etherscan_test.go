@@ -0,0 +1,88 @@
+package blockcheck
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/metachris/flashbots/common"
+)
+
+// swapExactTokensForTokens, called with testTxData
+const testRouterAbi = `[{"name":"swapExactTokensForTokens","type":"function","inputs":[{"name":"amountIn","type":"uint256"},{"name":"amountOutMin","type":"uint256"},{"name":"path","type":"address[]"},{"name":"to","type":"address"},{"name":"deadline","type":"uint256"}],"outputs":[{"name":"amounts","type":"uint256[]"}]}]`
+
+func newTestEtherscanServer(t *testing.T, response string) (requests *int32) {
+	requests = new(int32)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		if r.URL.Query().Get("apikey") != "test-key" {
+			t.Error("Wrong apikey:", r.URL.Query().Get("apikey"))
+		}
+		fmt.Fprint(w, response)
+	}))
+
+	etherscanUrl := common.EtherscanApiUrl
+	common.EtherscanApiUrl = server.URL
+	EtherscanApiKey = "test-key"
+	t.Cleanup(func() {
+		server.Close()
+		common.EtherscanApiUrl = etherscanUrl
+		EtherscanApiKey = ""
+		etherscanContractCache = make(map[string]*etherscanContract)
+	})
+	return requests
+}
+
+func TestCheckBlockForFailedTxEtherscan(t *testing.T) {
+	response := `{"status":"1","message":"OK","result":[{"ContractName":"UniswapV2Router02","ABI":` + strconv.Quote(testRouterAbi) + `}]}`
+	requests := newTestEtherscanServer(t, response)
+
+	key, _ := crypto.GenerateKey()
+	txs := []*types.Transaction{newLegacyTestTx(t, key, 0, 0), newLegacyTestTx(t, key, 1, 0)}
+	check := newTestBlockCheck(txs, []uint64{0, 0})
+	check.checkBlockForFailedTx()
+
+	for _, tx := range txs {
+		failedTx := check.FailedTx[tx.Hash().String()]
+		if failedTx.ContractName != "UniswapV2Router02" {
+			t.Error("Wrong ContractName:", failedTx.ContractName, "wanted:", "UniswapV2Router02")
+		}
+		wantMethod := "swapExactTokensForTokens(uint256,uint256,address[],address,uint256)"
+		if failedTx.Method != wantMethod {
+			t.Error("Wrong Method:", failedTx.Method, "wanted:", wantMethod)
+		}
+	}
+
+	// The contract is requested only once
+	if *requests != 1 {
+		t.Error("Wrong number of Etherscan requests:", *requests, "wanted:", 1)
+	}
+}
+
+func TestCheckBlockForFailedTxEtherscanError(t *testing.T) {
+	requests := newTestEtherscanServer(t, `{"status":"0","message":"NOTOK","result":"Invalid API Key"}`)
+
+	key, _ := crypto.GenerateKey()
+	txs := []*types.Transaction{newLegacyTestTx(t, key, 0, 0), newLegacyTestTx(t, key, 1, 0)}
+	check := newTestBlockCheck(txs, []uint64{0, 0})
+	check.checkBlockForFailedTx()
+
+	if len(check.FailedTx) != 2 {
+		t.Fatal("Wrong number of failed tx:", len(check.FailedTx), "wanted:", 2)
+	}
+	for _, failedTx := range check.FailedTx {
+		if failedTx.ContractName != "" || failedTx.Method != "" {
+			t.Error("ContractName and Method should be empty:", failedTx.ContractName, failedTx.Method)
+		}
+	}
+
+	// Errors are not cached
+	if *requests != 2 {
+		t.Error("Wrong number of Etherscan requests:", *requests, "wanted:", 2)
+	}
+}
@@ -1,11 +1,275 @@
 // Representation of a failed Flashbots or other 0-gas transaction (used in webserver)
 package blockcheck
 
-// FailedTx contains information about a failed 0-gas or Flashbots tx
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/metachris/flashbots/common"
+	"github.com/metachris/go-ethutils/utils"
+)
+
+const (
+	TxTypeLegacy     = "legacy"
+	TxTypeAccessList = "access-list"
+	TxTypeDynamicFee = "dynamic-fee"
+)
+
+const (
+	TxStatusFailed  = "failed"
+	TxStatusSuccess = "success" // only recorded with IncludeSuccessfulTx
+
+	// The tx succeeded, but an internal call reverted (only recorded with InternalCallTracer)
+	TxStatusInternalRevert = "internal-revert"
+)
+
+// FailedTx contains information about a failed 0-gas or Flashbots tx (or a successful one, with IncludeSuccessfulTx)
 type FailedTx struct {
 	Hash        string
+	Status      string // TxStatusFailed, TxStatusSuccess or TxStatusInternalRevert
 	IsFlashbots bool
 	From        string
-	To          string
+	To          string // empty for contract creation
+	Nonce       uint64 // of the sender
 	Block       uint64
+	TxIndex     uint   // position in the block (from the receipt)
+	Timestamp   uint64 // of the block (unix)
+	BaseFee     string // of the block (in wei), empty for blocks before London
+	TxType      string
+	Builder     string
+	Value       string // in wei
+	GasUsed     uint64
+
+	// Fees of the tx (in wei), to see why it is a 0-gas tx: GasPrice of legacy and access-list tx, and MaxFeePerGas and
+	// MaxPriorityFeePerGas (the tip, 0 for 0-gas tx) of dynamic-fee tx, with their effective GasPrice (base fee + tip)
+	GasPrice             string
+	MaxFeePerGas         string // empty for legacy and access-list tx
+	MaxPriorityFeePerGas string // empty for legacy and access-list tx
+
+	RevertReason string
+	Method       string // signature of the called method (with EtherscanApiKey, if the contract is verified)
+	MethodSig    string // signature of the called method from MethodSignatures (no API needed), or the 4-byte selector if unknown
+	ContractName string // name of the called contract (with EtherscanApiKey, if the contract is verified)
+	InternalCall string // of TxStatusInternalRevert: the first reverted internal call ("<type> <to>"), its revert reason is RevertReason
+
+	SenderFailures int // number of failed tx of the sender in this run, including this one (with RepeatedSenderThreshold)
+
+	BundleID   string // synthetic id ("<block>-<n>") of the likely failed bundle, empty if the tx isn't grouped with others
+	BundleSize int    // number of failed tx with the same BundleID
+
+	Reorged bool // the block was replaced by another block at the same height (watch mode)
+
+	FlashbotsUnknown bool // the block was checked without the Flashbots API data (watch mode, full backlog), IsFlashbots may be wrong
+}
+
+// IsFailed returns false for successful tx, also with internal reverts (tx without Status, i.e. from older stored data,
+// count as failed)
+func (failedTx *FailedTx) IsFailed() bool {
+	return failedTx.Status != TxStatusSuccess && failedTx.Status != TxStatusInternalRevert
+}
+
+// IncludeSuccessfulTx also records the successful Flashbots and other 0-gas tx in BlockCheck.SuccessfulTx. They are
+// subject to the same filters as failed tx, but don't add errors.
+var IncludeSuccessfulTx bool
+
+// OnlyFlashbotsTx only records Flashbots tx (by the Flashbots API, or by the fee recipient of the block). The other
+// 0-gas tx are skipped right after the classification, without recovering the sender, tracing or other lookups.
+var OnlyFlashbotsTx bool
+
+// LogTxDecisions logs every tx of a checked block with its classification, to debug why a tx was (not) recorded. Very
+// chatty, for debugging only.
+var LogTxDecisions bool
+
+// FailedTxFilter decides if a failed tx is recorded in a BlockCheck (if nil, all are recorded)
+var FailedTxFilter func(failedTx *FailedTx) bool
+
+// FailedTxMinValue is the minimum value (in wei) of a failed tx to be recorded (if nil, all are recorded)
+var FailedTxMinValue *big.Int
+
+// FailedTxMinGasUsed is the minimum gas used by a failed tx to be recorded
+var FailedTxMinGasUsed uint64
+
+func (d *Detector) isFailedTxIncluded(failedTx *FailedTx) bool {
+	if d.MinValue != nil && common.StrToBigInt(failedTx.Value).Cmp(d.MinValue) < 0 {
+		return false
+	}
+	if failedTx.GasUsed < d.MinGasUsed {
+		return false
+	}
+	return d.Filter == nil || d.Filter(failedTx)
+}
+
+// DeduplicateFailedTx records every failed tx hash only once per run. Later occurrences (i.e. the tx is mined again
+// after a reorg, or a block is checked again) are skipped and counted in BlockCheck.DuplicateFailedTx.
+var DeduplicateFailedTx bool
+
+// failedTxState is the run state of the failed tx detection (with Deduplicate and RepeatedSenderThreshold). The zero
+// value is ready to use, the maps are created on first use.
+type failedTxState struct {
+	lock           sync.Mutex
+	seenFailedTx   map[string]bool // lowercase hash
+	senderFailures map[string]int  // lowercase sender -> failed tx
+}
+
+// Run state of CheckBlock, a Detector has its own
+var packageFailedTxState failedTxState
+
+// markSeen marks the hash as seen and returns true if it was already seen before
+func (s *failedTxState) markSeen(hash string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.seenFailedTx == nil {
+		s.seenFailedTx = make(map[string]bool)
+	}
+	if s.seenFailedTx[hash] {
+		return true
+	}
+	s.seenFailedTx[hash] = true
+	return false
+}
+
+// countFailure counts a failed tx of the sender and returns its number of failed tx so far
+func (s *failedTxState) countFailure(sender string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.senderFailures == nil {
+		s.senderFailures = make(map[string]int)
+	}
+	s.senderFailures[sender] += 1
+	return s.senderFailures[sender]
+}
+
+// isDuplicateFailedTx returns true if the failed tx was already recorded (with Deduplicate), and marks it as seen
+func (d *Detector) isDuplicateFailedTx(failedTx *FailedTx) bool {
+	if !d.Deduplicate {
+		return false
+	}
+
+	return d.runState().markSeen(strings.ToLower(failedTx.Hash))
+}
+
+// RepeatedSenderThreshold marks the failed tx of senders that had more failed tx than this in the current run, i.e.
+// bots that keep sending a reverting bundle (0 disables counting)
+var RepeatedSenderThreshold int
+
+// repeatedSenderMarker is part of the error message of failed tx from repeated senders
+const repeatedSenderMarker = "failure from this sender"
+
+// countSenderFailure counts the failed tx for its sender and sets SenderFailures
+func (d *Detector) countSenderFailure(failedTx *FailedTx) {
+	if d.RepeatedSenderThreshold == 0 {
+		return
+	}
+
+	failedTx.SenderFailures = d.runState().countFailure(strings.ToLower(failedTx.From))
+}
+
+func (d *Detector) repeatedSenderMsg(failedTx *FailedTx) string {
+	if d.RepeatedSenderThreshold == 0 || failedTx.SenderFailures <= d.RepeatedSenderThreshold {
+		return ""
+	}
+	return fmt.Sprintf(" (%s %s)", ordinal(failedTx.SenderFailures), repeatedSenderMarker)
+}
+
+// ordinal returns 1st, 2nd, 3rd, 4th, ..., 11th, 12th, 13th, ..., 21st, ...
+func ordinal(n int) string {
+	suffix := "th"
+	switch {
+	case n%100 >= 11 && n%100 <= 13:
+	case n%10 == 1:
+		suffix = "st"
+	case n%10 == 2:
+		suffix = "nd"
+	case n%10 == 3:
+		suffix = "rd"
+	}
+	return fmt.Sprintf("%d%s", n, suffix)
+}
+
+// IsZeroGasTx returns true for Flashbots-like transactions, which have data and don't pay the miner through gas:
+// legacy transactions with 0 gas price, and EIP-1559 transactions with 0 priority fee.
+func IsZeroGasTx(tx *types.Transaction) bool {
+	return len(tx.Data()) > 0 && isZeroGasPrice(tx)
+}
+
+// isZeroGasPrice returns true if the gas price (the priority fee for EIP-1559 transactions) is 0
+func isZeroGasPrice(tx *types.Transaction) bool {
+	if tx.Type() == types.DynamicFeeTxType {
+		return utils.IsBigIntZero(tx.GasTipCap())
+	}
+	return utils.IsBigIntZero(tx.GasPrice())
+}
+
+// newFailedTx returns the FailedTx of a tx of the block, with the fields of the tx, its receipt and the block. tx is nil
+// if a tx of the Flashbots API isn't in the block, then the hash is taken from the receipt and the fields of the tx
+// are left empty (Value is "0").
+func (b *BlockCheck) newFailedTx(tx *types.Transaction, receipt *types.Receipt, status string, isFlashbots bool, from string, to string) *FailedTx {
+	failedTx := &FailedTx{
+		Hash:        receipt.TxHash.String(),
+		Status:      status,
+		IsFlashbots: isFlashbots,
+		From:        from,
+		To:          to,
+		Block:       uint64(b.Number),
+		Timestamp:   b.EthBlock.Time(),
+		Builder:     GetBuilderName(b.EthBlock),
+		Value:       "0",
+		GasUsed:     receipt.GasUsed,
+		TxIndex:     receipt.TransactionIndex,
+	}
+	if b.EthBlock.BaseFee() != nil {
+		failedTx.BaseFee = b.EthBlock.BaseFee().String()
+	}
+	if tx != nil {
+		failedTx.Hash = tx.Hash().String()
+		failedTx.TxType = TxTypeName(tx)
+		failedTx.Value = tx.Value().String()
+		failedTx.Nonce = tx.Nonce()
+		failedTx.MethodSig = methodSig(tx)
+		failedTx.setFees(tx, b.EthBlock.BaseFee())
+	}
+	return failedTx
+}
+
+// txAddresses returns the sender and the recipient of a tx (the recipient is empty for contract creation)
+func txAddresses(tx *types.Transaction) (from ethcommon.Address, to string) {
+	from, _ = types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if tx.To() != nil {
+		to = tx.To().String()
+	}
+	return from, to
+}
+
+// setFees sets GasPrice, MaxFeePerGas and MaxPriorityFeePerGas from the tx. The effective gas price of dynamic-fee tx
+// needs the base fee of the block, without it GasPrice is empty.
+func (failedTx *FailedTx) setFees(tx *types.Transaction, baseFee *big.Int) {
+	if tx.Type() != types.DynamicFeeTxType {
+		failedTx.GasPrice = tx.GasPrice().String()
+		return
+	}
+
+	failedTx.MaxFeePerGas = tx.GasFeeCap().String()
+	failedTx.MaxPriorityFeePerGas = tx.GasTipCap().String()
+	if baseFee != nil {
+		gasPrice := new(big.Int).Add(baseFee, tx.GasTipCap())
+		if gasPrice.Cmp(tx.GasFeeCap()) > 0 {
+			gasPrice = tx.GasFeeCap()
+		}
+		failedTx.GasPrice = gasPrice.String()
+	}
+}
+
+func TxTypeName(tx *types.Transaction) string {
+	switch tx.Type() {
+	case types.AccessListTxType:
+		return TxTypeAccessList
+	case types.DynamicFeeTxType:
+		return TxTypeDynamicFee
+	default:
+		return TxTypeLegacy
+	}
 }
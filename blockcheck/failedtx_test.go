@@ -0,0 +1,530 @@
+package blockcheck
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/metachris/flashbots/api"
+	"github.com/metachris/flashbots/logging"
+	"github.com/metachris/go-ethutils/blockswithtx"
+)
+
+var testChainId = big.NewInt(1)
+var testToAddress = ethcommon.HexToAddress("0x7a250d5630B4cF539739dF2C5dAcb4c659F2488D")
+var testTxData = []byte{0x38, 0xed, 0x17, 0x39}
+
+func signTestTx(t testing.TB, key *ecdsa.PrivateKey, txData types.TxData) *types.Transaction {
+	tx, err := types.SignNewTx(key, types.LatestSignerForChainID(testChainId), txData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func newLegacyTestTx(t testing.TB, key *ecdsa.PrivateKey, nonce uint64, gasPrice int64) *types.Transaction {
+	return signTestTx(t, key, &types.LegacyTx{Nonce: nonce, GasPrice: big.NewInt(gasPrice), Gas: 100_000, To: &testToAddress, Data: testTxData})
+}
+
+func newDynamicFeeTestTx(t testing.TB, key *ecdsa.PrivateKey, nonce uint64, gasTipCap int64) *types.Transaction {
+	return signTestTx(t, key, &types.DynamicFeeTx{ChainID: testChainId, Nonce: nonce, GasTipCap: big.NewInt(gasTipCap), GasFeeCap: big.NewInt(100e9), Gas: 100_000, To: &testToAddress, Data: testTxData})
+}
+
+// newTestBlockCheck returns a BlockCheck (without Flashbots API data) for a block with the given transactions and receipt status
+func newTestBlockCheck(txs []*types.Transaction, statuses []uint64) *BlockCheck {
+	header := &types.Header{Number: big.NewInt(13_000_000), BaseFee: big.NewInt(50e9)}
+	receipts := make([]*types.Receipt, len(txs))
+	for i, tx := range txs {
+		receipts[i] = &types.Receipt{Type: tx.Type(), Status: statuses[i], TxHash: tx.Hash(), TransactionIndex: uint(i)}
+	}
+
+	block := types.NewBlock(header, txs, nil, receipts, trie.NewStackTrie(nil))
+	blockWithTx := &blockswithtx.BlockWithTxReceipts{Block: block, TxReceipts: make(map[ethcommon.Hash]*types.Receipt)}
+	for _, receipt := range receipts {
+		blockWithTx.TxReceipts[receipt.TxHash] = receipt
+	}
+
+	return &BlockCheck{
+		Number:              block.Number().Int64(),
+		BlockWithTxReceipts: blockWithTx,
+		EthBlock:            block,
+	}
+}
+
+func TestIsZeroGasTx(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+
+	if !IsZeroGasTx(newLegacyTestTx(t, key, 0, 0)) {
+		t.Error("legacy tx with 0 gas price should be a 0-gas tx")
+	}
+	if IsZeroGasTx(newLegacyTestTx(t, key, 0, 1e9)) {
+		t.Error("legacy tx with gas price should not be a 0-gas tx")
+	}
+	if !IsZeroGasTx(newDynamicFeeTestTx(t, key, 0, 0)) {
+		t.Error("dynamic-fee tx with 0 priority fee should be a 0-gas tx")
+	}
+	if IsZeroGasTx(newDynamicFeeTestTx(t, key, 0, 2e9)) {
+		t.Error("dynamic-fee tx with priority fee should not be a 0-gas tx")
+	}
+
+	txWithoutData := signTestTx(t, key, &types.LegacyTx{GasPrice: big.NewInt(0), Gas: 21_000, To: &testToAddress})
+	if IsZeroGasTx(txWithoutData) {
+		t.Error("tx without data should not be a 0-gas tx")
+	}
+}
+
+func TestCheckBlockForFailedTx(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+
+	failedLegacyTx := newLegacyTestTx(t, key, 0, 0)
+	failedDynamicFeeTx := newDynamicFeeTestTx(t, key, 1, 0)
+	successfulDynamicFeeTx := newDynamicFeeTestTx(t, key, 2, 0)
+	failedTxWithTip := newDynamicFeeTestTx(t, key, 3, 2e9)
+
+	txs := []*types.Transaction{failedLegacyTx, failedDynamicFeeTx, successfulDynamicFeeTx, failedTxWithTip}
+	check := newTestBlockCheck(txs, []uint64{0, 0, 1, 0})
+	check.checkBlockForFailedTx()
+
+	if len(check.FailedTx) != 2 {
+		t.Fatal("Wrong number of failed tx:", len(check.FailedTx), "wanted:", 2)
+	}
+
+	expectedTxTypes := map[*types.Transaction]string{failedLegacyTx: TxTypeLegacy, failedDynamicFeeTx: TxTypeDynamicFee}
+	for tx, txType := range expectedTxTypes {
+		failedTx, found := check.FailedTx[tx.Hash().String()]
+		if !found {
+			t.Error("Failed tx not found:", tx.Hash())
+			continue
+		}
+		if failedTx.TxType != txType {
+			t.Error("Wrong TxType:", failedTx.TxType, "wanted:", txType)
+		}
+		if failedTx.From != sender.String() {
+			t.Error("Wrong sender:", failedTx.From, "wanted:", sender)
+		}
+		if failedTx.Nonce != tx.Nonce() {
+			t.Error("Wrong Nonce:", failedTx.Nonce, "wanted:", tx.Nonce())
+		}
+		if failedTx.IsFlashbots {
+			t.Error("Should not be a Flashbots tx:", tx.Hash())
+		}
+	}
+
+	if txIndex := check.FailedTx[failedDynamicFeeTx.Hash().String()].TxIndex; txIndex != 1 {
+		t.Error("Wrong TxIndex:", txIndex, "wanted:", 1)
+	}
+}
+
+func TestCheckBlockForFailedTxMinValue(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	lowValueTx := signTestTx(t, key, &types.LegacyTx{Nonce: 0, GasPrice: big.NewInt(0), Gas: 100_000, To: &testToAddress, Value: big.NewInt(1e15), Data: testTxData})
+	highValueTx := signTestTx(t, key, &types.LegacyTx{Nonce: 1, GasPrice: big.NewInt(0), Gas: 100_000, To: &testToAddress, Value: big.NewInt(2e18), Data: testTxData})
+
+	FailedTxMinValue = big.NewInt(1e18)
+	defer func() { FailedTxMinValue = nil }()
+
+	check := newTestBlockCheck([]*types.Transaction{lowValueTx, highValueTx}, []uint64{0, 0})
+	check.checkBlockForFailedTx()
+
+	if len(check.FailedTx) != 1 {
+		t.Fatal("Wrong number of failed tx:", len(check.FailedTx), "wanted:", 1)
+	}
+	failedTx, found := check.FailedTx[highValueTx.Hash().String()]
+	if !found {
+		t.Fatal("Failed tx not found:", highValueTx.Hash())
+	}
+	if failedTx.Value != "2000000000000000000" {
+		t.Error("Wrong Value:", failedTx.Value, "wanted:", "2000000000000000000")
+	}
+}
+
+// testEthClient is a fake common.EthClient. CallContract reverts with revertErr, the other methods are not used by the
+// check and return errTestEthClientNotImplemented.
+type testEthClient struct {
+	revertErr error
+	calls     int32
+}
+
+var errTestEthClientNotImplemented = errors.New("not implemented by testEthClient")
+
+func (c *testEthClient) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	return nil, errTestEthClientNotImplemented
+}
+
+func (c *testEthClient) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	return nil, errTestEthClientNotImplemented
+}
+
+func (c *testEthClient) BlockByHash(ctx context.Context, hash ethcommon.Hash) (*types.Block, error) {
+	return nil, errTestEthClientNotImplemented
+}
+
+func (c *testEthClient) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	atomic.AddInt32(&c.calls, 1)
+	return nil, c.revertErr
+}
+
+func (c *testEthClient) ChainID(ctx context.Context) (*big.Int, error) {
+	return testChainId, nil
+}
+
+func TestCheckBlockForFailedTxCases(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+
+	client := &testEthClient{revertErr: testRevertError{data: testRevertData(t, "STF")}}
+	RevertReasonClient = client
+	defer func() { RevertReasonClient = nil }()
+
+	testCases := []struct {
+		name            string
+		tx              *types.Transaction
+		status          uint64
+		isFlashbots     bool // tx is in the Flashbots API data of the block
+		wantFailed      bool
+		wantIsFlashbots bool
+	}{
+		{name: "successful 0-gas tx", tx: newLegacyTestTx(t, key, 0, 0), status: 1, wantFailed: false},
+		{name: "failed Flashbots tx", tx: newDynamicFeeTestTx(t, key, 0, 2e9), status: 0, isFlashbots: true, wantFailed: true, wantIsFlashbots: true},
+		{name: "failed 0-gas tx", tx: newDynamicFeeTestTx(t, key, 0, 0), status: 0, wantFailed: true, wantIsFlashbots: false},
+		{name: "failed tx with gas price", tx: newLegacyTestTx(t, key, 0, 50e9), status: 0, wantFailed: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			atomic.StoreInt32(&client.calls, 0)
+			check := newTestBlockCheck([]*types.Transaction{tc.tx}, []uint64{tc.status})
+			if tc.isFlashbots {
+				check.FlashbotsTransactions = []api.FlashbotsTransaction{
+					{Hash: tc.tx.Hash().String(), EoaAddress: sender.String(), ToAddress: testToAddress.String(), BlockNumber: check.Number, BundleType: api.BundleTypeFlashbots},
+				}
+			}
+			check.checkBlockForFailedTx()
+
+			failedTx, found := check.FailedTx[tc.tx.Hash().String()]
+			if found != tc.wantFailed {
+				t.Fatal("Wrong failed tx detection:", found, "wanted:", tc.wantFailed)
+			}
+
+			wantCalls := int32(0)
+			if tc.wantFailed {
+				wantCalls = 1
+			}
+			if calls := atomic.LoadInt32(&client.calls); calls != wantCalls {
+				t.Error("Wrong number of eth_calls:", calls, "wanted:", wantCalls)
+			}
+
+			if !found {
+				if len(check.Errors) != 0 {
+					t.Error("Wrong errors:", check.Errors, "wanted: none")
+				}
+				return
+			}
+			if failedTx.IsFlashbots != tc.wantIsFlashbots {
+				t.Error("Wrong IsFlashbots:", failedTx.IsFlashbots, "wanted:", tc.wantIsFlashbots)
+			}
+			if failedTx.From != sender.String() {
+				t.Error("Wrong sender:", failedTx.From, "wanted:", sender)
+			}
+			if failedTx.RevertReason != "STF" {
+				t.Error("Wrong RevertReason:", failedTx.RevertReason, "wanted:", "STF")
+			}
+			if check.HasFailedFlashbotsTx != tc.wantIsFlashbots {
+				t.Error("Wrong HasFailedFlashbotsTx:", check.HasFailedFlashbotsTx, "wanted:", tc.wantIsFlashbots)
+			}
+		})
+	}
+}
+
+func TestCheckBlockForFailedTxContractCreation(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	contractCreationTx := signTestTx(t, key, &types.LegacyTx{Nonce: 0, GasPrice: big.NewInt(0), Gas: 100_000, To: nil, Data: testTxData})
+
+	RevertReasonClient = &testEthClient{revertErr: testRevertError{data: testRevertData(t, "out of gas")}}
+	defer func() { RevertReasonClient = nil }()
+
+	check := newTestBlockCheck([]*types.Transaction{contractCreationTx}, []uint64{0})
+	check.checkBlockForFailedTx()
+
+	failedTx, found := check.FailedTx[contractCreationTx.Hash().String()]
+	if !found {
+		t.Fatal("Failed tx not found:", contractCreationTx.Hash())
+	}
+	if failedTx.To != "" {
+		t.Error("Wrong To:", failedTx.To, "wanted: empty string")
+	}
+	if failedTx.RevertReason != "out of gas" {
+		t.Error("Wrong RevertReason:", failedTx.RevertReason, "wanted:", "out of gas")
+	}
+}
+
+func TestCheckBlockForFailedTxRepeatedSender(t *testing.T) {
+	RepeatedSenderThreshold = 2
+	defer func() {
+		RepeatedSenderThreshold = 0
+		packageFailedTxState.senderFailures = nil
+	}()
+
+	key, _ := crypto.GenerateKey()
+	txs := []*types.Transaction{newLegacyTestTx(t, key, 0, 0), newLegacyTestTx(t, key, 1, 0), newLegacyTestTx(t, key, 2, 0)}
+	check := newTestBlockCheck(txs, []uint64{0, 0, 0})
+	check.checkBlockForFailedTx()
+
+	for i, tx := range txs {
+		if failedTx := check.FailedTx[tx.Hash().String()]; failedTx.SenderFailures != i+1 {
+			t.Error("Wrong SenderFailures:", failedTx.SenderFailures, "wanted:", i+1)
+		}
+	}
+
+	// Only the failed tx above the threshold are marked
+	if len(check.Errors) != 3 {
+		t.Fatal("Wrong number of errors:", len(check.Errors), "wanted:", 3)
+	}
+	if strings.Contains(check.Errors[1], repeatedSenderMarker) {
+		t.Error("Error should not be marked:", check.Errors[1])
+	}
+	if !strings.Contains(check.Errors[2], "(3rd failure from this sender)") {
+		t.Error("Error should be marked as 3rd failure:", check.Errors[2])
+	}
+}
+
+func TestOrdinal(t *testing.T) {
+	expected := map[int]string{1: "1st", 2: "2nd", 3: "3rd", 4: "4th", 11: "11th", 12: "12th", 13: "13th", 21: "21st", 102: "102nd", 111: "111th"}
+	for n, want := range expected {
+		if got := ordinal(n); got != want {
+			t.Error("Wrong ordinal:", got, "wanted:", want)
+		}
+	}
+}
+
+func TestCheckBlockForFailedTxMinGasUsed(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	cheapTx := newLegacyTestTx(t, key, 0, 0)
+	expensiveTx := newLegacyTestTx(t, key, 1, 0)
+
+	FailedTxMinGasUsed = 50_000
+	defer func() { FailedTxMinGasUsed = 0 }()
+
+	check := newTestBlockCheck([]*types.Transaction{cheapTx, expensiveTx}, []uint64{0, 0})
+	check.BlockWithTxReceipts.TxReceipts[cheapTx.Hash()].GasUsed = 25_000
+	check.BlockWithTxReceipts.TxReceipts[expensiveTx.Hash()].GasUsed = 80_000
+	check.checkBlockForFailedTx()
+
+	if len(check.FailedTx) != 1 {
+		t.Fatal("Wrong number of failed tx:", len(check.FailedTx), "wanted:", 1)
+	}
+	failedTx, found := check.FailedTx[expensiveTx.Hash().String()]
+	if !found {
+		t.Fatal("Failed tx not found:", expensiveTx.Hash())
+	}
+	if failedTx.GasUsed != 80_000 {
+		t.Error("Wrong GasUsed:", failedTx.GasUsed, "wanted:", 80_000)
+	}
+	if !strings.Contains(check.Errors[0], "gas used: 80000") {
+		t.Error("Error should contain the gas used:", check.Errors[0])
+	}
+}
+
+func TestCheckBlockForFailedTxIncludeSuccess(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	successfulTx := newLegacyTestTx(t, key, 0, 0)
+	failedTx := newLegacyTestTx(t, key, 1, 0)
+	paidTx := newLegacyTestTx(t, key, 2, 1)
+	txs := []*types.Transaction{successfulTx, failedTx, paidTx}
+
+	// By default only failed tx are recorded
+	check := newTestBlockCheck(txs, []uint64{1, 0, 1})
+	check.checkBlockForFailedTx()
+	if len(check.TxList()) != 1 || len(check.SuccessfulTx) != 0 {
+		t.Fatal("Wrong number of tx:", len(check.TxList()), "wanted:", 1)
+	}
+
+	IncludeSuccessfulTx = true
+	defer func() { IncludeSuccessfulTx = false }()
+
+	check = newTestBlockCheck(txs, []uint64{1, 0, 1})
+	check.checkBlockForFailedTx()
+
+	list := check.TxList()
+	if len(list) != 2 {
+		t.Fatal("Wrong number of tx:", len(list), "wanted:", 2)
+	}
+	if list[0].Hash != successfulTx.Hash().String() || list[0].Status != TxStatusSuccess || list[0].IsFailed() {
+		t.Error("Wrong first tx:", list[0].Hash, list[0].Status)
+	}
+	if list[1].Hash != failedTx.Hash().String() || list[1].Status != TxStatusFailed || !list[1].IsFailed() {
+		t.Error("Wrong second tx:", list[1].Hash, list[1].Status)
+	}
+
+	// Successful tx don't count as errors
+	if len(check.FailedTx) != 1 || len(check.Errors) != 1 || check.ErrorCounter.Failed0GasTx != 1 {
+		t.Error("Wrong failed tx / errors:", len(check.FailedTx), len(check.Errors), check.ErrorCounter.Failed0GasTx)
+	}
+}
+
+func TestCheckBlockForFailedTxBlockInfo(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	txs := []*types.Transaction{newLegacyTestTx(t, key, 0, 0)}
+
+	check := newTestBlockCheck(txs, []uint64{0})
+	check.checkBlockForFailedTx()
+	failedTx := check.FailedTx[txs[0].Hash().String()]
+	if failedTx.BaseFee != "50000000000" {
+		t.Error("Wrong BaseFee:", failedTx.BaseFee, "wanted:", "50000000000")
+	}
+
+	// Pre-London block without base fee
+	check = newTestBlockCheck(txs, []uint64{0})
+	check.EthBlock = types.NewBlockWithHeader(&types.Header{Number: big.NewInt(12_000_000), Time: 1_620_000_000}).WithBody(txs, nil)
+	check.checkBlockForFailedTx()
+	failedTx = check.FailedTx[txs[0].Hash().String()]
+	if failedTx.Timestamp != 1_620_000_000 {
+		t.Error("Wrong Timestamp:", failedTx.Timestamp, "wanted:", 1_620_000_000)
+	}
+	if failedTx.BaseFee != "" {
+		t.Error("BaseFee should be empty:", failedTx.BaseFee)
+	}
+}
+
+func TestCheckBlockForFailedTxFees(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	txs := []*types.Transaction{newLegacyTestTx(t, key, 0, 0), newDynamicFeeTestTx(t, key, 1, 0)}
+
+	check := newTestBlockCheck(txs, []uint64{0, 0})
+	check.checkBlockForFailedTx()
+	legacyTx := check.FailedTx[txs[0].Hash().String()]
+	if legacyTx.GasPrice != "0" || legacyTx.MaxFeePerGas != "" || legacyTx.MaxPriorityFeePerGas != "" {
+		t.Error("Wrong fees of legacy tx:", legacyTx.GasPrice, legacyTx.MaxFeePerGas, legacyTx.MaxPriorityFeePerGas)
+	}
+
+	// Base fee 50 gwei, max fee 100 gwei, no tip
+	dynamicFeeTx := check.FailedTx[txs[1].Hash().String()]
+	if dynamicFeeTx.GasPrice != "50000000000" || dynamicFeeTx.MaxFeePerGas != "100000000000" || dynamicFeeTx.MaxPriorityFeePerGas != "0" {
+		t.Error("Wrong fees of dynamic-fee tx:", dynamicFeeTx.GasPrice, dynamicFeeTx.MaxFeePerGas, dynamicFeeTx.MaxPriorityFeePerGas)
+	}
+
+	// The effective gas price is capped by the max fee
+	failedTx := &FailedTx{}
+	failedTx.setFees(txs[1], big.NewInt(150e9))
+	if failedTx.GasPrice != "100000000000" {
+		t.Error("Wrong capped GasPrice:", failedTx.GasPrice, "wanted:", "100000000000")
+	}
+	failedTx = &FailedTx{}
+	failedTx.setFees(txs[1], nil)
+	if failedTx.GasPrice != "" {
+		t.Error("GasPrice should be empty without base fee:", failedTx.GasPrice)
+	}
+}
+
+func TestCheckBlockForFailedTxDeduplicate(t *testing.T) {
+	DeduplicateFailedTx = true
+	defer func() {
+		DeduplicateFailedTx = false
+		packageFailedTxState.seenFailedTx = nil
+	}()
+
+	key, _ := crypto.GenerateKey()
+	tx := newLegacyTestTx(t, key, 0, 0)
+
+	check := newTestBlockCheck([]*types.Transaction{tx}, []uint64{0})
+	check.checkBlockForFailedTx()
+	if len(check.FailedTx) != 1 || check.DuplicateFailedTx != 0 {
+		t.Fatal("Wrong number of failed / duplicate tx:", len(check.FailedTx), check.DuplicateFailedTx, "wanted:", 1, 0)
+	}
+
+	// Same tx again (i.e. after a reorg)
+	check = newTestBlockCheck([]*types.Transaction{tx, newLegacyTestTx(t, key, 1, 0)}, []uint64{0, 0})
+	check.checkBlockForFailedTx()
+	if len(check.FailedTx) != 1 || check.DuplicateFailedTx != 1 {
+		t.Error("Wrong number of failed / duplicate tx:", len(check.FailedTx), check.DuplicateFailedTx, "wanted:", 1, 1)
+	}
+	if _, found := check.FailedTx[tx.Hash().String()]; found {
+		t.Error("Duplicate tx should not be recorded:", tx.Hash())
+	}
+}
+
+func TestCheckBlockForFailedTxLogDecisions(t *testing.T) {
+	LogTxDecisions = true
+	defer func() { LogTxDecisions = false }()
+
+	var out bytes.Buffer
+	if err := logging.Setup(logging.FormatJson, "info", &out); err != nil {
+		t.Fatal(err)
+	}
+	defer logging.Setup(logging.FormatText, "info", os.Stdout)
+
+	key, _ := crypto.GenerateKey()
+	txs := []*types.Transaction{newLegacyTestTx(t, key, 0, 0), newLegacyTestTx(t, key, 1, 0), newLegacyTestTx(t, key, 2, 1e9)}
+	check := newTestBlockCheck(txs, []uint64{0, 1, 0})
+	check.checkBlockForFailedTx()
+	logging.Log.Sync()
+
+	expectedDecisions := []string{"recorded: failed 0-gas tx", "skipped: successful", "skipped: not a 0-gas tx with data"}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != len(expectedDecisions) {
+		t.Fatal("Wrong number of log lines:", len(lines), "wanted:", len(expectedDecisions))
+	}
+	for i, line := range lines {
+		entry := make(map[string]interface{})
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatal("Invalid JSON log line:", line, err)
+		}
+		if entry["decision"] != expectedDecisions[i] {
+			t.Error("Wrong decision:", entry["decision"], "wanted:", expectedDecisions[i])
+		}
+		if entry["hash"] != txs[i].Hash().String() {
+			t.Error("Wrong hash:", entry["hash"], "wanted:", txs[i].Hash())
+		}
+	}
+}
+
+func TestPlainLinks(t *testing.T) {
+	msg := "failed 0-gas tx [0xabc](<https://etherscan.io/tx/0xabc>) from [0xdef](<https://etherscan.io/address/0xdef>) - gas used: 0"
+	expected := "failed 0-gas tx 0xabc (https://etherscan.io/tx/0xabc) from 0xdef (https://etherscan.io/address/0xdef) - gas used: 0"
+	if plain := plainLinks(msg); plain != expected {
+		t.Error("Wrong plainLinks:", plain, "wanted:", expected)
+	}
+}
+
+func TestCheckBlockForFailedTxOnlyFlashbots(t *testing.T) {
+	OnlyFlashbotsTx = true
+	defer func() { OnlyFlashbotsTx = false }()
+
+	key, _ := crypto.GenerateKey()
+	flashbotsTx := newLegacyTestTx(t, key, 0, 1e9)
+	txs := []*types.Transaction{flashbotsTx, newLegacyTestTx(t, key, 1, 0)}
+
+	// Only the failed tx of the Flashbots API is recorded, not the other failed 0-gas tx
+	check := newTestBlockCheck(txs, []uint64{0, 0})
+	check.FlashbotsTransactions = []api.FlashbotsTransaction{{Hash: flashbotsTx.Hash().String(), BlockNumber: check.Number}}
+	check.checkBlockForFailedTx()
+	list := check.FailedTxList()
+	if len(list) != 1 || list[0].Hash != flashbotsTx.Hash().String() || !list[0].IsFlashbots {
+		t.Fatal("Wrong failed tx:", list)
+	}
+	if check.ErrorCounter.FailedFlashbotsTx != 1 || check.ErrorCounter.Failed0GasTx != 0 || check.HasFailed0GasTx {
+		t.Error("Wrong error counts:", check.ErrorCounter)
+	}
+
+	// In blocks of a Flashbots fee recipient, the failed 0-gas tx are Flashbots tx
+	check = newTestBlockCheck(txs, []uint64{0, 0})
+	check.ClassifiedByFeeRecipient = true
+	check.IsFlashbotsFeeRecipient = true
+	check.checkBlockForFailedTx()
+	if len(check.FailedTxList()) != 1 || check.ErrorCounter.FailedFlashbotsTx != 1 {
+		t.Error("Wrong number of failed tx:", len(check.FailedTxList()), "wanted:", 1)
+	}
+}
@@ -0,0 +1,69 @@
+// Offline classification of Flashbots tx by the fee recipient (coinbase) of the block, instead of the Flashbots API
+package blockcheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// FlashbotsFeeRecipients maps known fee recipient addresses (lowercase) to whether their blocks are built by Flashbots.
+// If the fee recipient of a block is in it, its 0-gas tx are classified without the Flashbots API: all as Flashbots tx
+// (true), or none (false). Blocks of other fee recipients are still checked with the Flashbots API. Nil disables it.
+var FlashbotsFeeRecipients map[string]bool
+
+// feeRecipientsFile is the format of LoadFlashbotsFeeRecipients
+type feeRecipientsFile struct {
+	Flashbots []string `json:"flashbots"` // allowlist: blocks with these fee recipients are Flashbots blocks
+	Other     []string `json:"other"`     // denylist: blocks with these fee recipients have no Flashbots tx
+}
+
+// LoadFlashbotsFeeRecipients sets FlashbotsFeeRecipients from a JSON file ({"flashbots": ["0x..."], "other": ["0x..."]}).
+// If an address isn't a valid 20-byte hex address or is in both lists, it fails with an error naming it and keeps the
+// current mapping.
+func LoadFlashbotsFeeRecipients(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var file feeRecipientsFile
+	err = json.Unmarshal(data, &file)
+	if err != nil {
+		return err
+	}
+
+	feeRecipients := make(map[string]bool)
+	add := func(addresses []string, isFlashbots bool) error {
+		for _, address := range addresses {
+			if !ethcommon.IsHexAddress(address) {
+				return fmt.Errorf("invalid fee recipient address in %s: %s", path, address)
+			}
+			key := strings.ToLower(ethcommon.HexToAddress(address).Hex())
+			if current, found := feeRecipients[key]; found && current != isFlashbots {
+				return fmt.Errorf("fee recipient in both lists of %s: %s", path, address)
+			}
+			feeRecipients[key] = isFlashbots
+		}
+		return nil
+	}
+	if err = add(file.Flashbots, true); err != nil {
+		return err
+	}
+	if err = add(file.Other, false); err != nil {
+		return err
+	}
+	FlashbotsFeeRecipients = feeRecipients
+	return nil
+}
+
+// classifyByFeeRecipient returns whether the block is a Flashbots block by its fee recipient, known is false if the fee
+// recipient isn't in FlashbotsFeeRecipients (then the Flashbots API decides)
+func classifyByFeeRecipient(block *types.Block) (isFlashbots bool, known bool) {
+	isFlashbots, known = FlashbotsFeeRecipients[strings.ToLower(block.Coinbase().Hex())]
+	return isFlashbots, known
+}
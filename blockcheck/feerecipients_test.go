@@ -0,0 +1,80 @@
+package blockcheck
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestLoadFlashbotsFeeRecipients(t *testing.T) {
+	defer func() { FlashbotsFeeRecipients = nil }()
+
+	dir, err := ioutil.TempDir("", "feerecipients")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "fee-recipients.json")
+	ioutil.WriteFile(path, []byte(`{"flashbots": ["0xDAFEA492D9c6733ae3d56b7Ed1ADB60692c98Bc5"], "other": ["0x123"]}`), 0644)
+	err = LoadFlashbotsFeeRecipients(path)
+	if err == nil || !strings.Contains(err.Error(), "0x123") || FlashbotsFeeRecipients != nil {
+		t.Error("Expected an error naming the invalid address:", err, FlashbotsFeeRecipients)
+	}
+
+	ioutil.WriteFile(path, []byte(`{"flashbots": ["0xDAFEA492D9c6733ae3d56b7Ed1ADB60692c98Bc5"], "other": ["0xdafea492d9c6733ae3d56b7ed1adb60692c98bc5"]}`), 0644)
+	err = LoadFlashbotsFeeRecipients(path)
+	if err == nil || !strings.Contains(err.Error(), "both lists") {
+		t.Error("Expected an error for an address in both lists:", err)
+	}
+
+	ioutil.WriteFile(path, []byte(`{"flashbots": ["0xDAFEA492D9c6733ae3d56b7Ed1ADB60692c98Bc5"], "other": ["0x95222290DD7278Aa3Ddd389Cc1E1d165CC4BAfe5"]}`), 0644)
+	if err = LoadFlashbotsFeeRecipients(path); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		coinbase    string
+		isFlashbots bool
+		known       bool
+	}{
+		{"0xdafea492d9c6733ae3d56b7ed1adb60692c98bc5", true, true},
+		{"0x95222290dd7278aa3ddd389cc1e1d165cc4bafe5", false, true},
+		{"0x4838b106fce9647bdf1e7877bf73ce8b0bad5f97", false, false},
+	} {
+		block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1), Coinbase: ethcommon.HexToAddress(c.coinbase)})
+		isFlashbots, known := classifyByFeeRecipient(block)
+		if isFlashbots != c.isFlashbots || known != c.known {
+			t.Error("Wrong classification of", c.coinbase, ":", isFlashbots, known, "wanted:", c.isFlashbots, c.known)
+		}
+	}
+}
+
+func TestCheckBlockForFailedTxByFeeRecipient(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	txs := []*types.Transaction{newLegacyTestTx(t, key, 0, 0), newLegacyTestTx(t, key, 1, 0), newLegacyTestTx(t, key, 2, 1e9)}
+	check := newTestBlockCheck(txs, []uint64{0, 0, 0})
+	check.ClassifiedByFeeRecipient = true
+	check.IsFlashbotsFeeRecipient = true
+	check.checkBlockForFailedTx()
+
+	list := check.FailedTxList()
+	if len(list) != 2 {
+		t.Fatal("Wrong number of failed tx:", len(list), "wanted:", 2)
+	}
+	for _, failedTx := range list {
+		if !failedTx.IsFlashbots || failedTx.BundleSize != 2 {
+			t.Error("Wrong failed tx:", failedTx.Hash, failedTx.IsFlashbots, failedTx.BundleSize)
+		}
+	}
+	if check.ErrorCounter.FailedFlashbotsTx != 2 || check.ErrorCounter.Failed0GasTx != 0 || !check.HasFailedFlashbotsTx {
+		t.Error("Wrong error counts:", check.ErrorCounter)
+	}
+}
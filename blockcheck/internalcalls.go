@@ -0,0 +1,72 @@
+// Detection of reverted internal calls of successful 0-gas transactions (with debug_traceTransaction)
+package blockcheck
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/metachris/flashbots/common"
+	"github.com/metachris/flashbots/logging"
+)
+
+// InternalCallTracer returns the call tree of a tx, i.e. with common.TraceTransaction. If not nil, successful 0-gas tx
+// with a reverted internal call are recorded in BlockCheck.SuccessfulTx with TxStatusInternalRevert. One trace per
+// successful 0-gas tx, this needs a node with the debug API.
+var InternalCallTracer func(hash ethcommon.Hash) (*common.CallFrame, error)
+
+var internalCallTracerLock sync.Mutex
+
+// getInternalRevert returns the first reverted internal call of the tx, nil if there is none or tracing failed. If the
+// node doesn't support tracing, a warning is logged and InternalCallTracer is disabled.
+func getInternalRevert(hash ethcommon.Hash) *common.CallFrame {
+	internalCallTracerLock.Lock()
+	tracer := InternalCallTracer
+	internalCallTracerLock.Unlock()
+	if tracer == nil {
+		return nil
+	}
+
+	frame, err := tracer(hash)
+	if errors.Is(err, common.ErrTracingNotSupported) {
+		logging.Log.Warnw("Eth node doesn't support tracing, internal reverts are not detected", "error", err)
+		internalCallTracerLock.Lock()
+		InternalCallTracer = nil
+		internalCallTracerLock.Unlock()
+		return nil
+	} else if err != nil {
+		logging.Log.Warnw("Error tracing tx, internal reverts are not detected for it", "hash", hash.Hex(), "error", err)
+		return nil
+	}
+	return frame.FirstRevertedCall()
+}
+
+// internalRevertReason returns the decoded Error(string) revert reason of the call, or else its error
+func internalRevertReason(call *common.CallFrame) string {
+	if revertData, err := hexutil.Decode(call.Output); err == nil {
+		if reason, err := abi.UnpackRevert(revertData); err == nil {
+			return reason
+		}
+	}
+	return call.Error
+}
+
+// recordInternalRevert adds the successful tx with the reverted internal call to SuccessfulTx, and returns the decision
+// (for LogTxDecisions)
+func (b *BlockCheck) recordInternalRevert(d *Detector, tx *types.Transaction, receipt *types.Receipt, isFlashbotsTx bool, call *common.CallFrame) (decision string) {
+	from, to := txAddresses(tx)
+	internalRevertTx := b.newFailedTx(tx, receipt, TxStatusInternalRevert, isFlashbotsTx, from.String(), to)
+	internalRevertTx.RevertReason = internalRevertReason(call)
+	internalRevertTx.InternalCall = call.Type + " " + call.To
+	if !d.isFailedTxIncluded(internalRevertTx) {
+		return "skipped: successful with internal revert, filtered out"
+	}
+	if EtherscanApiKey != "" {
+		setEtherscanInfo(internalRevertTx, tx)
+	}
+	b.SuccessfulTx[internalRevertTx.Hash] = internalRevertTx
+	return "recorded: successful 0-gas tx with internal revert"
+}
@@ -0,0 +1,77 @@
+package blockcheck
+
+import (
+	"fmt"
+	"testing"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/metachris/flashbots/common"
+)
+
+func TestCheckBlockForFailedTxInternalRevert(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	internalRevertTx := newLegacyTestTx(t, key, 0, 0)
+	successfulTx := newLegacyTestTx(t, key, 1, 0)
+	failedTx := newLegacyTestTx(t, key, 2, 0)
+	txs := []*types.Transaction{internalRevertTx, successfulTx, failedTx}
+
+	traced := make(map[ethcommon.Hash]bool)
+	InternalCallTracer = func(hash ethcommon.Hash) (*common.CallFrame, error) {
+		traced[hash] = true
+		frame := &common.CallFrame{Type: "CALL", To: testToAddress.Hex()}
+		if hash == internalRevertTx.Hash() {
+			frame.Calls = []common.CallFrame{{Type: "CALL", To: "0x1111111111111111111111111111111111111111", Output: testRevertData(t, "UniswapV2: K"), Error: "execution reverted"}}
+		}
+		return frame, nil
+	}
+	defer func() { InternalCallTracer = nil }()
+
+	check := newTestBlockCheck(txs, []uint64{1, 1, 0})
+	check.checkBlockForFailedTx()
+
+	// Only the successful tx are traced
+	if len(traced) != 2 || traced[failedTx.Hash()] {
+		t.Error("Wrong traced tx:", traced)
+	}
+
+	list := check.TxList()
+	if len(list) != 2 {
+		t.Fatal("Wrong number of tx:", len(list), "wanted:", 2)
+	}
+	if list[0].Hash != internalRevertTx.Hash().String() || list[0].Status != TxStatusInternalRevert || list[0].IsFailed() {
+		t.Error("Wrong first tx:", list[0].Hash, list[0].Status)
+	}
+	if list[0].InternalCall != "CALL 0x1111111111111111111111111111111111111111" || list[0].RevertReason != "UniswapV2: K" {
+		t.Error("Wrong internal call:", list[0].InternalCall, list[0].RevertReason)
+	}
+
+	// Internal reverts don't count as errors
+	if len(check.FailedTx) != 1 || len(check.Errors) != 1 {
+		t.Error("Wrong failed tx / errors:", len(check.FailedTx), len(check.Errors))
+	}
+}
+
+func TestCheckBlockForFailedTxTracingNotSupported(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	txs := []*types.Transaction{newLegacyTestTx(t, key, 0, 0), newLegacyTestTx(t, key, 1, 0)}
+
+	traces := 0
+	InternalCallTracer = func(hash ethcommon.Hash) (*common.CallFrame, error) {
+		traces += 1
+		return nil, fmt.Errorf("%w: method not found", common.ErrTracingNotSupported)
+	}
+	defer func() { InternalCallTracer = nil }()
+
+	check := newTestBlockCheck(txs, []uint64{1, 1})
+	check.checkBlockForFailedTx()
+
+	// Tracing is disabled after the first error
+	if traces != 1 || InternalCallTracer != nil {
+		t.Error("Wrong number of traces:", traces, "wanted:", 1)
+	}
+	if len(check.TxList()) != 0 {
+		t.Error("Wrong number of tx:", len(check.TxList()), "wanted:", 0)
+	}
+}
@@ -0,0 +1,81 @@
+// Decoding the method of a tx from its 4-byte selector, with built-in signatures of common DEX, lending and token methods
+package blockcheck
+
+import (
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// knownMethodSignatures are the methods that MethodSignatures is built from
+var knownMethodSignatures = []string{
+	// Uniswap V2 router (and forks like Sushiswap)
+	"swapExactTokensForTokens(uint256,uint256,address[],address,uint256)",
+	"swapTokensForExactTokens(uint256,uint256,address[],address,uint256)",
+	"swapExactETHForTokens(uint256,address[],address,uint256)",
+	"swapTokensForExactETH(uint256,uint256,address[],address,uint256)",
+	"swapExactTokensForETH(uint256,uint256,address[],address,uint256)",
+	"swapETHForExactTokens(uint256,address[],address,uint256)",
+	"swapExactTokensForTokensSupportingFeeOnTransferTokens(uint256,uint256,address[],address,uint256)",
+	"swapExactETHForTokensSupportingFeeOnTransferTokens(uint256,address[],address,uint256)",
+	"swapExactTokensForETHSupportingFeeOnTransferTokens(uint256,uint256,address[],address,uint256)",
+	"addLiquidity(address,address,uint256,uint256,uint256,uint256,address,uint256)",
+	"addLiquidityETH(address,uint256,uint256,uint256,address,uint256)",
+	"removeLiquidity(address,address,uint256,uint256,uint256,address,uint256)",
+	"removeLiquidityETH(address,uint256,uint256,uint256,address,uint256)",
+
+	// Uniswap V2 pair and V3 pool
+	"swap(uint256,uint256,address,bytes)",
+	"swap(address,bool,int256,uint160,bytes)",
+
+	// Uniswap V3 router and universal router
+	"exactInputSingle((address,address,uint24,address,uint256,uint256,uint256,uint160))",
+	"exactInput((bytes,address,uint256,uint256,uint256))",
+	"exactOutputSingle((address,address,uint24,address,uint256,uint256,uint256,uint160))",
+	"exactOutput((bytes,address,uint256,uint256,uint256))",
+	"multicall(bytes[])",
+	"multicall(uint256,bytes[])",
+	"execute(bytes,bytes[])",
+	"execute(bytes,bytes[],uint256)",
+
+	// Curve
+	"exchange(int128,int128,uint256,uint256)",
+	"exchange_underlying(int128,int128,uint256,uint256)",
+
+	// Lending (Aave, Compound)
+	"flashLoan(address,address[],uint256[],uint256[],address,bytes,uint16)",
+	"liquidationCall(address,address,address,uint256,bool)",
+	"liquidateBorrow(address,uint256,address)",
+
+	// ERC-20 and WETH
+	"transfer(address,uint256)",
+	"transferFrom(address,address,uint256)",
+	"approve(address,uint256)",
+	"deposit()",
+	"withdraw(uint256)",
+}
+
+// MethodSignatures maps 4-byte selectors (0x-prefixed hex) to method signatures, used for FailedTx.MethodSig
+var MethodSignatures = methodSignaturesBySelector(knownMethodSignatures)
+
+func methodSignaturesBySelector(signatures []string) map[string]string {
+	ret := make(map[string]string, len(signatures))
+	for _, signature := range signatures {
+		ret[hexutil.Encode(crypto.Keccak256([]byte(signature))[:4])] = signature
+	}
+	return ret
+}
+
+// methodSig returns the signature of the called method from MethodSignatures, the selector (0x-prefixed hex) if it's
+// unknown, or an empty string for tx without a selector (i.e. plain transfers and contract creation)
+func methodSig(tx *types.Transaction) string {
+	if tx == nil || tx.To() == nil || len(tx.Data()) < 4 {
+		return ""
+	}
+
+	selector := hexutil.Encode(tx.Data()[:4])
+	if signature, found := MethodSignatures[selector]; found {
+		return signature
+	}
+	return selector
+}
@@ -0,0 +1,29 @@
+package blockcheck
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestMethodSig(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	for data, expected := range map[string]string{
+		string(testTxData):                     "swapExactTokensForTokens(uint256,uint256,address[],address,uint256)",
+		"\xa9\x05\x9c\xbb\x00\x00":             "transfer(address,uint256)",
+		"\x12\x34\x56\x78\x00\x00\x00\x00\x00": "0x12345678",
+		"\x12\x34":                             "",
+	} {
+		tx := signTestTx(t, key, &types.LegacyTx{GasPrice: big.NewInt(0), Gas: 100_000, To: &testToAddress, Data: []byte(data)})
+		if sig := methodSig(tx); sig != expected {
+			t.Error("Wrong methodSig:", sig, "wanted:", expected)
+		}
+	}
+
+	contractCreation := signTestTx(t, key, &types.LegacyTx{GasPrice: big.NewInt(0), Gas: 100_000, Data: testTxData})
+	if sig := methodSig(contractCreation); sig != "" {
+		t.Error("Wrong methodSig of contract creation:", sig, "wanted: empty")
+	}
+}
@@ -0,0 +1,93 @@
+// Decoding the revert reason of failed transactions
+package blockcheck
+
+import (
+	"context"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/metachris/flashbots/common"
+)
+
+// RevertReasonClient is used to get the revert reason of failed tx via eth_call. This is expensive, nil disables it.
+var RevertReasonClient common.EthClient
+
+// RevertReasonWorkers is the number of concurrent eth_calls to get the revert reasons of the failed tx in a block
+var RevertReasonWorkers int = 8
+
+// GetRevertReason replays the transaction with eth_call on the state before its block, and decodes the Error(string)
+// revert reason. Returns an empty string if the node returns no revert data or it can't be decoded.
+func GetRevertReason(client common.EthClient, tx *types.Transaction, from ethcommon.Address, blockNumber int64) string {
+	msg := ethereum.CallMsg{
+		From:       from,
+		To:         tx.To(),
+		Gas:        tx.Gas(),
+		Value:      tx.Value(),
+		Data:       tx.Data(),
+		AccessList: tx.AccessList(),
+	}
+
+	_, err := client.CallContract(context.Background(), msg, big.NewInt(blockNumber-1))
+	if err == nil {
+		return ""
+	}
+
+	// The revert data is part of the JSON-RPC error
+	dataErr, ok := err.(rpc.DataError)
+	if !ok {
+		return ""
+	}
+
+	hexData, ok := dataErr.ErrorData().(string)
+	if !ok {
+		return ""
+	}
+
+	revertData, err := hexutil.Decode(hexData)
+	if err != nil {
+		return ""
+	}
+
+	reason, err := abi.UnpackRevert(revertData)
+	if err != nil {
+		return ""
+	}
+	return reason
+}
+
+func revertReasonMsg(failedTx *FailedTx) string {
+	if failedTx.RevertReason == "" {
+		return ""
+	}
+	return " - revert reason: " + failedTx.RevertReason
+}
+
+// getRevertReasons sets the revert reasons of the pending failed tx, with up to RevertReasonWorkers concurrent eth_calls
+func getRevertReasons(pending []*pendingFailedTx, blockNumber int64) {
+	var wg sync.WaitGroup
+	pendingChan := make(chan *pendingFailedTx)
+
+	for w := 0; w < RevertReasonWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range pendingChan {
+				p.failedTx.RevertReason = GetRevertReason(RevertReasonClient, p.tx, p.from, blockNumber)
+			}
+		}()
+	}
+
+	for _, p := range pending {
+		if p.tx != nil {
+			pendingChan <- p
+		}
+	}
+	close(pendingChan)
+	wg.Wait()
+}
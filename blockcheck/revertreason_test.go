@@ -0,0 +1,107 @@
+package blockcheck
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// testRevertError is the JSON-RPC error of a reverted eth_call, with the Error(string) revert data
+type testRevertError struct {
+	data string
+}
+
+func (e testRevertError) Error() string          { return "execution reverted" }
+func (e testRevertError) ErrorCode() int         { return 3 }
+func (e testRevertError) ErrorData() interface{} { return e.data }
+
+// testEthService answers eth_call with a revert, after a delay (like a remote node)
+type testEthService struct {
+	delay      time.Duration
+	revertData string
+}
+
+func (s *testEthService) Call(ctx context.Context, args map[string]interface{}, blockNumber string) (hexutil.Bytes, error) {
+	time.Sleep(s.delay)
+	return nil, testRevertError{data: s.revertData}
+}
+
+// testRevertData returns the hex encoded Error(string) revert data for reason
+func testRevertData(t testing.TB, reason string) string {
+	stringType, _ := abi.NewType("string", "", nil)
+	packed, err := abi.Arguments{{Type: stringType}}.Pack(reason)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hexutil.Encode(append(crypto.Keccak256([]byte("Error(string)"))[:4], packed...))
+}
+
+func newTestRevertClient(t testing.TB, reason string, delay time.Duration) *ethclient.Client {
+	server := rpc.NewServer()
+	err := server.RegisterName("eth", &testEthService{delay: delay, revertData: testRevertData(t, reason)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ethclient.NewClient(rpc.DialInProc(server))
+}
+
+func TestCheckBlockForFailedTxRevertReason(t *testing.T) {
+	RevertReasonClient = newTestRevertClient(t, "UniswapV2: K", 0)
+	defer func() { RevertReasonClient = nil }()
+
+	key, _ := crypto.GenerateKey()
+	txs := []*types.Transaction{newLegacyTestTx(t, key, 0, 0), newLegacyTestTx(t, key, 1, 0)}
+	check := newTestBlockCheck(txs, []uint64{0, 0})
+	check.checkBlockForFailedTx()
+
+	for _, tx := range txs {
+		failedTx, found := check.FailedTx[tx.Hash().String()]
+		if !found {
+			t.Fatal("Failed tx not found:", tx.Hash())
+		}
+		if failedTx.RevertReason != "UniswapV2: K" {
+			t.Error("Wrong RevertReason:", failedTx.RevertReason, "wanted:", "UniswapV2: K")
+		}
+	}
+
+	// Errors are in block order (both tx are from the same sender, i.e. one failed bundle)
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	expectedMsg := fmt.Sprintf("failed 0-gas tx [%s](<https://etherscan.io/tx/%s>) from [%s](<https://etherscan.io/address/%s>) - gas used: 0 - revert reason: UniswapV2: K (2 txs in failed bundle 13000000-1)\n", txs[0].Hash(), txs[0].Hash(), from, from)
+	if len(check.Errors) != 2 || check.Errors[0] != expectedMsg {
+		t.Error("Wrong errors:", check.Errors, "wanted first:", expectedMsg)
+	}
+}
+
+// BenchmarkCheckBlockForFailedTx checks a block with 24 failed tx, with a node that needs 5ms per eth_call
+func BenchmarkCheckBlockForFailedTx(b *testing.B) {
+	RevertReasonClient = newTestRevertClient(b, "reverted", 5*time.Millisecond)
+	defer func() { RevertReasonClient = nil }()
+
+	key, _ := crypto.GenerateKey()
+	txs := make([]*types.Transaction, 24)
+	statuses := make([]uint64, 24)
+	for i := range txs {
+		txs[i] = newLegacyTestTx(b, key, uint64(i), 0)
+	}
+
+	for _, workers := range []int{1, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			defaultWorkers := RevertReasonWorkers
+			RevertReasonWorkers = workers
+			defer func() { RevertReasonWorkers = defaultWorkers }()
+
+			for i := 0; i < b.N; i++ {
+				check := newTestBlockCheck(txs, statuses)
+				check.checkBlockForFailedTx()
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package blockcheck
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/metachris/flashbots/api"
+	"github.com/metachris/flashbots/common"
+	"github.com/metachris/go-ethutils/addresslookup"
+)
+
+// TestCheckBlockRpcFixture runs the full CheckBlock path of block 13000000 from a fixture with the eth node and Flashbots
+// API responses (a synthetic block with a failed Flashbots tx and a successful tx with gas price). Fixtures of real
+// blocks can be recorded with block-watch -record-rpc.
+func TestCheckBlockRpcFixture(t *testing.T) {
+	fixture, err := common.LoadRpcFixture("testdata/rpc_failed_flashbots_tx.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(fixture)
+	defer server.Close()
+
+	defaultBaseUrl := api.BaseUrl
+	defer func() { api.BaseUrl = defaultBaseUrl }()
+	if err = api.SetBaseUrl(server.URL); err != nil {
+		t.Fatal(err)
+	}
+	AddressLookup = addresslookup.NewAddressLookupService(nil) // without downloading the address lists
+	defer func() { AddressLookup = nil }()
+
+	client, err := common.DialEthClient(server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := common.GetBlockWithTxReceipts(client, 13_000_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check, err := CheckBlock(block, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !check.HasFailedFlashbotsTx || check.HasFailed0GasTx {
+		t.Error("Wrong flags:", check.HasFailedFlashbotsTx, check.HasFailed0GasTx, "wanted: true false")
+	}
+	failedTxs := check.FailedTxList()
+	if len(failedTxs) != 1 {
+		t.Fatal("Wrong number of failed tx:", len(failedTxs), "wanted:", 1)
+	}
+
+	expected := FailedTx{
+		Hash:        "0xc575ff101558b63a174f24c90c97f623d3bc664d60dd53ee029493706e35c977",
+		Status:      TxStatusFailed,
+		IsFlashbots: true,
+		From:        "0x2c7536E3605D9C16a7a3D7b1898e529396a65c23",
+		To:          "0x7a250d5630B4cF539739dF2C5dAcb4c659F2488D",
+		Nonce:       7,
+		Block:       13_000_000,
+		TxIndex:     0,
+		Timestamp:   1627776000,
+		TxType:      TxTypeLegacy,
+		Builder:     "flashbots",
+		Value:       "0",
+		GasUsed:     128_808,
+		GasPrice:    "0",
+		MethodSig:   "swapExactTokensForTokens(uint256,uint256,address[],address,uint256)",
+	}
+	if *failedTxs[0] != expected {
+		t.Errorf("Wrong failed tx: %+v wanted: %+v", *failedTxs[0], expected)
+	}
+}
@@ -0,0 +1,54 @@
+// The likely failed bundles in the history, i.e. failed tx grouped by blockcheck (served at /bundles)
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/metachris/flashbots/blockcheck"
+)
+
+// FailedBundle is a group of failed tx of a block with the same BundleID
+type FailedBundle struct {
+	BundleID    string   `json:"bundleId"`
+	Block       uint64   `json:"block"`
+	IsFlashbots bool     `json:"isFlashbots"`
+	TxIndex     uint     `json:"txIndex"` // of the first tx
+	Txs         []string `json:"txs"`     // hashes, in block order
+	Senders     []string `json:"senders"` // in block order, without duplicates
+}
+
+// findFailedBundles returns the failed bundles of history, the newest block first. Entries restored from a database of
+// an older version have no BundleID and are skipped.
+func findFailedBundles(history []blockcheck.FailedTx) []FailedBundle {
+	failedTxs := make(map[string][]blockcheck.FailedTx) // BundleID -> failed tx
+	for _, failedTx := range history {
+		if failedTx.BundleID != "" && failedTx.IsFailed() {
+			failedTxs[failedTx.BundleID] = append(failedTxs[failedTx.BundleID], failedTx)
+		}
+	}
+
+	ret := make([]FailedBundle, 0, len(failedTxs))
+	for bundleId, txs := range failedTxs {
+		sort.Slice(txs, func(i, j int) bool { return txs[i].TxIndex < txs[j].TxIndex })
+
+		bundle := FailedBundle{BundleID: bundleId, Block: txs[0].Block, IsFlashbots: txs[0].IsFlashbots, TxIndex: txs[0].TxIndex}
+		seenSenders := make(map[string]bool)
+		for _, failedTx := range txs {
+			bundle.Txs = append(bundle.Txs, failedTx.Hash)
+			if sender := strings.ToLower(failedTx.From); !seenSenders[sender] {
+				seenSenders[sender] = true
+				bundle.Senders = append(bundle.Senders, failedTx.From)
+			}
+		}
+		ret = append(ret, bundle)
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Block != ret[j].Block {
+			return ret[i].Block > ret[j].Block
+		}
+		return ret[i].TxIndex < ret[j].TxIndex
+	})
+	return ret
+}
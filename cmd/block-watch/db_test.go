@@ -0,0 +1,148 @@
+package main
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/metachris/flashbots/blockcheck"
+)
+
+func tempDatabasePath(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "block-watch-db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, "failedtx.db")
+}
+
+func TestFailedTxDatabaseRoundTrip(t *testing.T) {
+	db, err := NewFailedTxDatabase("sqlite://"+tempDatabasePath(t), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	failedTxs := []blockcheck.FailedTx{
+		{Hash: "0x01", Status: blockcheck.TxStatusFailed, IsFlashbots: true, From: "0xa", To: "0xb", Nonce: 7, Block: 100, TxIndex: 3, Timestamp: 1630000000, BaseFee: "1000", TxType: "dynamic-fee", Builder: "flashbots", Value: "5", GasUsed: 21000, GasPrice: "1000", MaxFeePerGas: "2000", MaxPriorityFeePerGas: "0", RevertReason: "too late", MethodSig: "swap()", BundleID: "100-1", BundleSize: 2},
+		{Hash: "0x02", Status: blockcheck.TxStatusFailed, From: "0xc", Block: 101, GasUsed: 30000, FlashbotsUnknown: true},
+	}
+	if err := db.Insert(failedTxs...); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := db.LoadLatest(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, failedTxs) {
+		t.Error("Wrong failed tx:", loaded, "wanted:", failedTxs)
+	}
+
+	loaded, err = db.LoadLatest(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != 1 || loaded[0].Hash != "0x02" {
+		t.Error("Wrong latest failed tx:", loaded, "wanted:", failedTxs[1:])
+	}
+}
+
+func TestFailedTxDatabaseMigration(t *testing.T) {
+	path := tempDatabasePath(t)
+
+	// Table of the first version, without data
+	legacy, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = legacy.Exec(`CREATE TABLE failed_tx (
+	id           INTEGER PRIMARY KEY AUTOINCREMENT,
+	hash         TEXT NOT NULL UNIQUE,
+	is_flashbots BOOLEAN NOT NULL,
+	from_address TEXT NOT NULL,
+	to_address   TEXT NOT NULL,
+	block        INTEGER NOT NULL,
+	created_at   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+INSERT INTO failed_tx (hash, is_flashbots, from_address, to_address, block) VALUES ('0x01', true, '0xa', '0xb', 100);`)
+	legacy.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := NewFailedTxDatabase("sqlite://"+path, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	newTx := blockcheck.FailedTx{Hash: "0x02", Status: blockcheck.TxStatusFailed, From: "0xc", Block: 101, GasUsed: 30000}
+	if err := db.Insert(newTx); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := db.LoadLatest(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []blockcheck.FailedTx{{Hash: "0x01", IsFlashbots: true, From: "0xa", To: "0xb", Block: 100}, newTx}
+	if !reflect.DeepEqual(loaded, expected) {
+		t.Error("Wrong failed tx:", loaded, "wanted:", expected)
+	}
+}
+
+func TestFailedTxDatabaseReorg(t *testing.T) {
+	for _, removeReorged := range []bool{false, true} {
+		db, err := NewFailedTxDatabase("sqlite://"+tempDatabasePath(t), 100, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		db.removeReorged = removeReorged
+
+		// The failed tx of the replaced block are still buffered
+		block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(101)})
+		db.OnFailedTxs(block, []blockcheck.FailedTx{
+			{Hash: "0x01", Status: blockcheck.TxStatusFailed, Block: 100},
+			{Hash: "0x02", Status: blockcheck.TxStatusFailed, Block: 101},
+			{Hash: "0x03", Status: blockcheck.TxStatusFailed, Block: 101},
+		})
+		db.OnReorg(ReorgEvent{FromBlock: 101, ToBlock: 101, Depth: 1, NewBlock: 101})
+
+		// 0x03 is included again in the new block
+		if err := db.Insert(blockcheck.FailedTx{Hash: "0x03", Status: blockcheck.TxStatusFailed, Block: 102, GasUsed: 100}); err != nil {
+			t.Fatal(err)
+		}
+
+		loaded, err := db.LoadLatest(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := []blockcheck.FailedTx{
+			{Hash: "0x01", Status: blockcheck.TxStatusFailed, Block: 100},
+			{Hash: "0x02", Status: blockcheck.TxStatusFailed, Block: 101, Reorged: true},
+			{Hash: "0x03", Status: blockcheck.TxStatusFailed, Block: 102, GasUsed: 100},
+		}
+		if removeReorged {
+			expected = []blockcheck.FailedTx{expected[0], expected[2]}
+		}
+		if !reflect.DeepEqual(loaded, expected) {
+			t.Error("Wrong failed tx with removeReorged", removeReorged, ":", loaded, "wanted:", expected)
+		}
+		db.Close()
+	}
+}
+
+func TestLastByHash(t *testing.T) {
+	failedTxs := []blockcheck.FailedTx{{Hash: "0x01", Block: 1}, {Hash: "0x02", Block: 1}, {Hash: "0x01", Block: 2}}
+	expected := []blockcheck.FailedTx{{Hash: "0x02", Block: 1}, {Hash: "0x01", Block: 2}}
+	if got := lastByHash(failedTxs); !reflect.DeepEqual(got, expected) {
+		t.Error("Wrong failed tx:", got, "wanted:", expected)
+	}
+}
@@ -6,11 +6,17 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
-	"log"
 	"net/http"
 	"os"
 	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/metachris/flashbots/blockcheck"
+	"github.com/metachris/flashbots/common"
+	"github.com/metachris/flashbots/logging"
 )
 
 type DiscordWebhookPayload struct {
@@ -19,6 +25,10 @@ type DiscordWebhookPayload struct {
 
 var discordUrl string = os.Getenv("DISCORD_WEBHOOK")
 
+var discordClient = &http.Client{Timeout: 10 * time.Second}
+
+const discordQueueSize = 100
+
 // SendToDiscord splits one message into multiple if necessary (max size is 2k characters)
 func SendToDiscord(msg string) error {
 	if msg == "" {
@@ -47,6 +57,72 @@ func SendToDiscord(msg string) error {
 	}
 }
 
+// discordMessage are the failed tx of a block for one Discord (or Telegram) message
+type discordMessage struct {
+	blockNumber int64
+	failedTxs   []*blockcheck.FailedTx
+}
+
+// discordHandler sends the failed Flashbots tx of a block to the Discord webhook (and other failed 0-gas tx if
+// includeAll). The blocks are queued, the queue is drained by a single goroutine so a slow webhook never blocks block
+// processing.
+type discordHandler struct {
+	includeAll bool
+	queue      chan discordMessage
+}
+
+// NewDiscordHandler starts the goroutine that sends the queued failed tx to the Discord webhook
+func NewDiscordHandler(includeAll bool) *discordHandler {
+	h := &discordHandler{includeAll: includeAll, queue: make(chan discordMessage, discordQueueSize)}
+
+	go func() {
+		for msg := range h.queue {
+			err := SendFailedTxToDiscord(msg.blockNumber, msg.failedTxs)
+			if err != nil {
+				logging.Log.Errorw("Error sending failed tx to Discord", "block", msg.blockNumber, "error", err)
+			}
+		}
+	}()
+	return h
+}
+
+// OnFailedTxs adds the failed tx of the block to the Discord queue, or drops them if the queue is full
+func (h *discordHandler) OnFailedTxs(block *types.Block, failedTxs []blockcheck.FailedTx) {
+	discordFailedTxs := make([]*blockcheck.FailedTx, 0)
+	for _, failedTx := range failedTxs {
+		if failedTx.IsFailed() && (failedTx.IsFlashbots || h.includeAll) {
+			failedTx := failedTx // a copy, the queue outlives the call
+			discordFailedTxs = append(discordFailedTxs, &failedTx)
+		}
+	}
+
+	// All failed tx of a block are sent in a single message, to stay within Discord rate limits
+	if len(discordFailedTxs) > 0 {
+		select {
+		case h.queue <- discordMessage{blockNumber: block.Number().Int64(), failedTxs: discordFailedTxs}:
+		default:
+			logging.Log.Warnw("Discord queue is full, dropping failed tx", "block", block.Number(), "count", len(discordFailedTxs))
+		}
+	}
+}
+
+// SendFailedTxToDiscord sends one message with all failed transactions of a block
+func SendFailedTxToDiscord(blockNumber int64, failedTxs []*blockcheck.FailedTx) error {
+	msg := fmt.Sprintf("Block [%d](<%s>): %d failed tx\n", blockNumber, common.ExplorerBlockUrl(blockNumber), len(failedTxs))
+	for _, tx := range failedTxs {
+		txType := "0-gas"
+		if tx.IsFlashbots {
+			txType = "Flashbots"
+		}
+		to := "contract creation"
+		if tx.To != "" {
+			to = fmt.Sprintf("[%s](<%s>)", tx.To, common.ExplorerAddressUrl(tx.To))
+		}
+		msg += fmt.Sprintf("- failed %s tx [%s](<%s>) from [%s](<%s>) to %s\n", txType, tx.Hash, common.ExplorerTxUrl(tx.Hash), tx.From, common.ExplorerAddressUrl(tx.From), to)
+	}
+	return SendToDiscord(msg)
+}
+
 // _SendToDiscord sends to discord without any error checks
 func _SendToDiscord(msg string) error {
 	if len(discordUrl) == 0 {
@@ -59,18 +135,17 @@ func _SendToDiscord(msg string) error {
 		return err
 	}
 
-	res, err := http.Post(discordUrl, "application/json", bytes.NewBuffer(payloadBytes))
+	res, err := discordClient.Post(discordUrl, "application/json", bytes.NewBuffer(payloadBytes))
 	if err != nil {
 		return err
 	}
 
 	defer res.Body.Close()
-	log.Println("Discord response status:", res.Status)
+	logging.Log.Debugw("Discord response", "status", res.Status)
 
 	if res.StatusCode >= 300 {
 		bodyBytes, _ := ioutil.ReadAll(res.Body)
-		bodyString := string(bodyBytes)
-		log.Println(bodyString)
+		logging.Log.Errorw("Discord error response", "status", res.Status, "body", string(bodyBytes))
 	}
 	return nil
 }
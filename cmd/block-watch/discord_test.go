@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/metachris/flashbots/blockcheck"
+)
+
+func TestDiscordHandler(t *testing.T) {
+	received := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload DiscordWebhookPayload
+		json.NewDecoder(r.Body).Decode(&payload)
+		received <- payload.Content
+	}))
+	defer server.Close()
+
+	defer func(url string) { discordUrl = url }(discordUrl)
+	discordUrl = server.URL
+
+	h := NewDiscordHandler(false)
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(100)})
+	failedTxs := []blockcheck.FailedTx{
+		{Hash: "0x01", Status: blockcheck.TxStatusFailed, IsFlashbots: true, From: "0xa", To: "0xb", Block: 100},
+		{Hash: "0x02", Status: blockcheck.TxStatusFailed, From: "0xc", Block: 100},
+	}
+	h.OnFailedTxs(block, failedTxs)
+	failedTxs[0].Hash = "0x03" // the queued message has a copy
+
+	select {
+	case msg := <-received:
+		if !strings.Contains(msg, "1 failed tx") || !strings.Contains(msg, "0x01") || strings.Contains(msg, "0x02") {
+			t.Error("Wrong Discord message:", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Discord message not sent")
+	}
+}
@@ -0,0 +1,166 @@
+// Handling of failed Flashbots and other 0-gas transactions found in checked blocks
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/metachris/flashbots/blockcheck"
+	"github.com/metachris/flashbots/common"
+	"github.com/metachris/flashbots/logging"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// FailedTxHandler receives the failed transactions of checked blocks (in-memory history, database, notifications, ...)
+type FailedTxHandler interface {
+	// OnFailedTxs is called once for every checked block with failed transactions (in block order). With
+	// -include-success, failedTxs also contains the successful tx, with -include-internal the successful tx with
+	// internal reverts (see FailedTx.IsFailed).
+	OnFailedTxs(block *types.Block, failedTxs []blockcheck.FailedTx)
+}
+
+// FailedTxHistory holds the most recent failed transactions (served by the webserver). It is safe for concurrent use:
+// the watch loop adds entries while the webserver, the WebSocket hub and the TUI read them, so the entries are only
+// accessed through its methods.
+type FailedTxHistory struct {
+	lock sync.RWMutex
+	size int // maximum number of entries (0 means unbounded)
+	txs  []blockcheck.FailedTx
+
+	removeReorged bool // remove the failed tx of reorged blocks, instead of marking them as Reorged
+}
+
+func NewFailedTxHistory(size int) *FailedTxHistory {
+	if size == 0 {
+		logging.Log.Warn("History size is unbounded, memory usage will grow with every failed tx")
+	}
+	return &FailedTxHistory{size: size, txs: make([]blockcheck.FailedTx, 0, size)}
+}
+
+// Add adds failed transactions and removes the oldest entries that exceed the history size
+func (h *FailedTxHistory) Add(failedTxs ...blockcheck.FailedTx) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.txs = append(h.txs, failedTxs...)
+	if h.size > 0 && len(h.txs) > h.size {
+		h.txs = h.txs[len(h.txs)-h.size:]
+	}
+}
+
+// List returns a copy of the history, oldest entry first
+func (h *FailedTxHistory) List() []blockcheck.FailedTx {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	ret := make([]blockcheck.FailedTx, len(h.txs))
+	copy(ret, h.txs)
+	return ret
+}
+
+func (h *FailedTxHistory) OnFailedTxs(block *types.Block, failedTxs []blockcheck.FailedTx) {
+	h.Add(failedTxs...)
+}
+
+// logHandler logs every failed tx, or with a template (-format-template) prints it as one line to out
+type logHandler struct {
+	template *template.Template
+	out      io.Writer
+}
+
+// failedTxTemplateData is the data of -format-template: the fields of FailedTx, and Time (of the block, UTC)
+type failedTxTemplateData struct {
+	blockcheck.FailedTx
+	Time time.Time
+}
+
+func newFailedTxTemplateData(failedTx blockcheck.FailedTx) failedTxTemplateData {
+	return failedTxTemplateData{FailedTx: failedTx, Time: time.Unix(int64(failedTx.Timestamp), 0).UTC()}
+}
+
+// parseFormatTemplate parses a -format-template, and executes it once with an empty FailedTx, so that unknown fields
+// fail at startup instead of at the first failed tx
+func parseFormatTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("format-template").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tmpl.Execute(ioutil.Discard, newFailedTxTemplateData(blockcheck.FailedTx{}))
+	if err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+func (h *logHandler) OnFailedTxs(block *types.Block, failedTxs []blockcheck.FailedTx) {
+	for _, failedTx := range failedTxs {
+		if h.template != nil {
+			var line bytes.Buffer
+			err := h.template.Execute(&line, newFailedTxTemplateData(failedTx))
+			if err == nil {
+				fmt.Fprintln(h.out, strings.TrimSuffix(line.String(), "\n"))
+				continue
+			}
+			logging.Log.Errorw("Error executing format template", "hash", failedTx.Hash, "error", err)
+		}
+
+		msg := "Failed tx"
+		fields := []interface{}{"block", failedTx.Block, "hash", failedTx.Hash, "url", common.ExplorerTxUrl(failedTx.Hash), "from", failedTx.From, "to", failedTx.To, "isFlashbots", failedTx.IsFlashbots, "builder", failedTx.Builder, "method", failedTx.MethodSig, "gasUsed", failedTx.GasUsed, "senderFailures", failedTx.SenderFailures}
+		if failedTx.Status == blockcheck.TxStatusInternalRevert {
+			msg = "Internal revert in tx"
+			fields = append(fields, "internalCall", failedTx.InternalCall, "revertReason", failedTx.RevertReason)
+		} else if !failedTx.IsFailed() {
+			msg = "Successful tx"
+		}
+		if failedTx.FlashbotsUnknown {
+			fields = append(fields, "flashbotsUnknown", true)
+		}
+		logging.Log.Infow(msg, fields...)
+	}
+}
+
+// metricsHandler counts the failed tx for Prometheus. Every increment carries the tx hash and block as exemplar
+// (visible when scraped in the OpenMetrics format).
+type metricsHandler struct{}
+
+func (metricsHandler) OnFailedTxs(block *types.Block, failedTxs []blockcheck.FailedTx) {
+	for _, failedTx := range failedTxs {
+		if !failedTx.IsFailed() {
+			continue
+		}
+		counter := metricFailedTx.WithLabelValues(strconv.FormatBool(failedTx.IsFlashbots))
+		exemplar := prometheus.Labels{"tx_hash": failedTx.Hash, "block": strconv.FormatUint(failedTx.Block, 10)}
+		counter.(prometheus.ExemplarAdder).AddWithExemplar(1, exemplar)
+	}
+}
+
+// handleFailedTxs passes the failed (and with -include-success the successful) transactions of a checked block to all
+// handlers
+func handleFailedTxs(check *blockcheck.BlockCheck, handlers []FailedTxHandler) {
+	failedTxs := make([]blockcheck.FailedTx, 0)
+	for _, failedTx := range check.TxList() {
+		failedTxs = append(failedTxs, *failedTx)
+	}
+
+	if len(failedTxs) == 0 {
+		return
+	}
+
+	for _, handler := range handlers {
+		handler.OnFailedTxs(check.EthBlock, failedTxs)
+	}
+}
+
+// isFailedTxMatchingAddressFilter returns true if sender or recipient are in the -from / -to filters
+func isFailedTxMatchingAddressFilter(failedTx *blockcheck.FailedTx) bool {
+	return fromAddressFilter.Contains(failedTx.From) || toAddressFilter.Contains(failedTx.To)
+}
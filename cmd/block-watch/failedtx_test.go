@@ -0,0 +1,79 @@
+package main
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/metachris/flashbots/blockcheck"
+)
+
+// TestFailedTxHistoryConcurrent adds, lists and reorgs entries concurrently (run with -race)
+func TestFailedTxHistoryConcurrent(t *testing.T) {
+	history := NewFailedTxHistory(50)
+
+	var wg sync.WaitGroup
+	for writer := 0; writer < 4; writer++ {
+		wg.Add(1)
+		go func(writer int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				history.Add(blockcheck.FailedTx{Hash: strconv.Itoa(writer) + "-" + strconv.Itoa(i), Block: uint64(i)})
+			}
+		}(writer)
+	}
+	for reader := 0; reader < 4; reader++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				failedTxs := history.List()
+				if len(failedTxs) > 50 {
+					t.Error("Wrong history size:", len(failedTxs), "wanted at most:", 50)
+					return
+				}
+				for j := range failedTxs {
+					failedTxs[j].Reorged = true // the copy is not shared with the history
+				}
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := int64(0); i < 100; i += 10 {
+			history.OnReorg(ReorgEvent{FromBlock: i, ToBlock: i + 1, Depth: 2, NewBlock: i})
+		}
+	}()
+	wg.Wait()
+
+	failedTxs := history.List()
+	if len(failedTxs) != 50 {
+		t.Error("Wrong history size:", len(failedTxs), "wanted:", 50)
+	}
+	for _, failedTx := range failedTxs {
+		if failedTx.Reorged && failedTx.Block%10 > 1 {
+			t.Error("Wrong Reorged of block", failedTx.Block, ":", failedTx.Reorged, "wanted:", false)
+		}
+	}
+}
+
+func TestFailedTxHistoryOnReorg(t *testing.T) {
+	for _, removeReorged := range []bool{false, true} {
+		history := NewFailedTxHistory(10)
+		history.removeReorged = removeReorged
+		history.Add(blockcheck.FailedTx{Hash: "0x01", Block: 100}, blockcheck.FailedTx{Hash: "0x02", Block: 101}, blockcheck.FailedTx{Hash: "0x03", Block: 102})
+		history.OnReorg(ReorgEvent{FromBlock: 101, ToBlock: 101, Depth: 1, NewBlock: 101})
+
+		failedTxs := history.List()
+		if removeReorged {
+			if len(failedTxs) != 2 || failedTxs[0].Hash != "0x01" || failedTxs[1].Hash != "0x03" {
+				t.Error("Wrong history with removeReorged:", failedTxs)
+			}
+			continue
+		}
+		if len(failedTxs) != 3 || failedTxs[0].Reorged || !failedTxs[1].Reorged || failedTxs[2].Reorged {
+			t.Error("Wrong Reorged of history:", failedTxs)
+		}
+	}
+}
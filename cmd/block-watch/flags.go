@@ -0,0 +1,110 @@
+// Custom command line flag types, and loading flag values from a JSON config file
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+)
+
+// addressListFlag is a repeatable flag of comma-separated addresses (stored in checksummed form)
+type addressListFlag map[string]bool
+
+func (f addressListFlag) String() string {
+	addresses := make([]string, 0, len(f))
+	for address := range f {
+		addresses = append(addresses, address)
+	}
+	return strings.Join(addresses, ",")
+}
+
+// Set adds the addresses, and fails on the first one that isn't a 20-byte hex address
+func (f addressListFlag) Set(value string) error {
+	for _, address := range strings.Split(value, ",") {
+		address = strings.TrimSpace(address)
+		if address == "" {
+			continue
+		}
+		if !ethcommon.IsHexAddress(address) {
+			return fmt.Errorf("invalid address: %s", address)
+		}
+		f[ethcommon.HexToAddress(address).Hex()] = true
+	}
+	return nil
+}
+
+func (f addressListFlag) Contains(address string) bool {
+	if !ethcommon.IsHexAddress(address) { // i.e. empty To of contract creations
+		return false
+	}
+	return f[ethcommon.HexToAddress(address).Hex()]
+}
+
+// loadConfigFile sets flags from a JSON file with flag names as keys. Flags passed on the command line take precedence.
+func loadConfigFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	config := make(map[string]interface{})
+	err = json.Unmarshal(data, &config)
+	if err != nil {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	passedFlags := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		passedFlags[f.Name] = true
+	})
+
+	for name, value := range config {
+		if flag.Lookup(name) == nil || name == "config" {
+			return fmt.Errorf("invalid config field: %s", name)
+		}
+
+		if passedFlags[name] {
+			continue
+		}
+
+		valueStr, err := configValueToString(value)
+		if err != nil {
+			return fmt.Errorf("invalid config field %s: %w", name, err)
+		}
+
+		err = flag.Set(name, valueStr)
+		if err != nil {
+			return fmt.Errorf("invalid config field %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func configValueToString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []interface{}: // list, eg. of addresses
+		values := make([]string, len(v))
+		for i, entry := range v {
+			s, err := configValueToString(entry)
+			if err != nil {
+				return "", err
+			}
+			values[i] = s
+		}
+		return strings.Join(values, ","), nil
+	default:
+		return "", fmt.Errorf("unsupported value: %v", value)
+	}
+}
@@ -0,0 +1,136 @@
+// Watching the mempool for pending 0-gas tx (Flashbots candidates before they are mined), served at /pending
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/metachris/flashbots/blockcheck"
+	"github.com/metachris/flashbots/logging"
+)
+
+// Pending tx are fetched concurrently, so the subscription isn't dropped by the node if the mempool is busy
+const mempoolFetchWorkers = 4
+
+// PendingTx is a pending 0-gas tx with data (a "pending Flashbots candidate")
+type PendingTx struct {
+	Hash      string    `json:"hash"`
+	From      string    `json:"from"`
+	To        string    `json:"to"` // empty for contract creation
+	TxType    string    `json:"txType"`
+	Value     string    `json:"value"` // in wei
+	FirstSeen time.Time `json:"firstSeen"`
+}
+
+// PendingTxHistory holds the most recently seen pending candidates (separate from the failed tx history)
+type PendingTxHistory struct {
+	lock sync.RWMutex
+	size int // maximum number of entries (0 means unbounded)
+	txs  []PendingTx
+}
+
+func NewPendingTxHistory(size int) *PendingTxHistory {
+	return &PendingTxHistory{size: size, txs: make([]PendingTx, 0, size)}
+}
+
+// Add adds a pending tx and removes the oldest entries that exceed the history size
+func (h *PendingTxHistory) Add(tx PendingTx) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.txs = append(h.txs, tx)
+	if h.size > 0 && len(h.txs) > h.size {
+		h.txs = h.txs[len(h.txs)-h.size:]
+	}
+}
+
+// List returns a copy of the history, oldest entry first
+func (h *PendingTxHistory) List() []PendingTx {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	ret := make([]PendingTx, len(h.txs))
+	copy(ret, h.txs)
+	return ret
+}
+
+// watchMempool subscribes to the pending tx of the node and adds the 0-gas tx with data to pending, until ctx is done.
+// If the node doesn't support pending tx subscriptions (i.e. HTTP), a warning is logged and mempool mode is disabled.
+func watchMempool(ctx context.Context, uri string, pending *PendingTxHistory) {
+	if isHttpUri(uri) {
+		logging.Log.Warnw("Mempool mode needs a WebSocket or IPC node, disabled", "node", uri)
+		return
+	}
+
+	rpcClient, err := rpc.DialContext(ctx, uri)
+	if err != nil {
+		logging.Log.Warnw("Mempool mode disabled, connection error", "node", uri, "error", err)
+		return
+	}
+	defer rpcClient.Close()
+
+	hashes := make(chan ethcommon.Hash, 1024)
+	sub, err := gethclient.New(rpcClient).SubscribePendingTransactions(ctx, hashes)
+	if err != nil {
+		logging.Log.Warnw("Mempool mode disabled, the node doesn't support pending tx subscriptions", "node", uri, "error", err)
+		return
+	}
+	logging.Log.Infow("Watching mempool", "node", uri)
+
+	client := ethclient.NewClient(rpcClient)
+	var wg sync.WaitGroup
+	for i := 0; i < mempoolFetchWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for hash := range hashes {
+				checkPendingTx(ctx, client, hash, pending)
+			}
+		}()
+	}
+
+	select {
+	case <-ctx.Done():
+	case err := <-sub.Err():
+		logging.Log.Warnw("Mempool subscription error, mempool mode disabled", "node", uri, "error", err)
+	}
+
+	sub.Unsubscribe() // no more sends on hashes after Unsubscribe returns
+	close(hashes)
+	wg.Wait()
+}
+
+// checkPendingTx fetches the pending tx and adds it to pending if it is a 0-gas tx with data
+func checkPendingTx(ctx context.Context, client *ethclient.Client, hash ethcommon.Hash, pending *PendingTxHistory) {
+	tx, isPending, err := client.TransactionByHash(ctx, hash)
+	if err != nil || !isPending { // already mined or dropped
+		return
+	}
+
+	if !blockcheck.IsZeroGasTx(tx) {
+		return
+	}
+
+	from, _ := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	to := ""
+	if tx.To() != nil {
+		to = tx.To().String()
+	}
+
+	pendingTx := PendingTx{
+		Hash:      tx.Hash().String(),
+		From:      from.String(),
+		To:        to,
+		TxType:    blockcheck.TxTypeName(tx),
+		Value:     tx.Value().String(),
+		FirstSeen: time.Now().UTC(),
+	}
+	pending.Add(pendingTx)
+	logging.Log.Infow("Pending Flashbots candidate", "hash", pendingTx.Hash, "from", pendingTx.From, "to", pendingTx.To)
+}
@@ -0,0 +1,45 @@
+// Prometheus metrics (served at /metrics)
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	metricFailedTx = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "flashbots_failed_tx_total",
+		Help: "Number of failed Flashbots and other 0-gas transactions",
+	}, []string{"is_flashbots"})
+
+	metricBlockHeight = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "flashbots_processed_block_height",
+		Help: "Height of the latest processed block",
+	})
+
+	metricFlashbotsApiLag = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "flashbots_api_lag_blocks",
+		Help: "Number of blocks the Flashbots API is behind the latest block of the eth nodes",
+	})
+
+	metricBlockBacklogSize = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "flashbots_block_backlog_size",
+		Help: "Number of blocks waiting for the Flashbots API",
+	})
+
+	metricBacklogOverflow = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "flashbots_block_backlog_overflow_total",
+		Help: "Number of blocks beyond -max-backlog, processed without the Flashbots API or dropped (by policy)",
+	}, []string{"policy"})
+
+	metricReorgs = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "flashbots_reorgs_total",
+		Help: "Number of detected reorgs of processed blocks",
+	})
+
+	metricBlockProcessingDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "flashbots_block_processing_seconds",
+		Help:    "Time needed to check a block",
+		Buckets: prometheus.ExponentialBuckets(0.01, 2, 12),
+	})
+)
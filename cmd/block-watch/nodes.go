@@ -0,0 +1,167 @@
+// Ethereum node connections, which deliver new block headers via subscription or polling
+package main
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/metachris/flashbots/common"
+	"github.com/metachris/flashbots/logging"
+)
+
+const resubscribeMaxBackoff = 30 * time.Second
+
+type ethNode struct {
+	Uri     string
+	Client  *ethclient.Client
+	headers chan *types.Header // subscription channel
+	active  int32              // 1 while the subscription is active (or polling works), accessed atomically
+}
+
+// numActiveNodes is the number of nodes with an active subscription (or polling), accessed atomically
+var numActiveNodes int32
+
+// setActive updates the subscription state of the node
+func (n *ethNode) setActive(active bool) {
+	if active && atomic.CompareAndSwapInt32(&n.active, 0, 1) {
+		atomic.AddInt32(&numActiveNodes, 1)
+	} else if !active && atomic.CompareAndSwapInt32(&n.active, 1, 0) {
+		atomic.AddInt32(&numActiveNodes, -1)
+	}
+}
+
+// nodeHeader is a new block header, and the node that delivered it
+type nodeHeader struct {
+	Node   *ethNode
+	Header *types.Header
+}
+
+// dialEthNode connects to the node, headers are sent with every request (see common.DialEthClient)
+func dialEthNode(uri string, headers common.RpcHeaders) (*ethNode, error) {
+	client, err := common.DialEthClient(uri, headers)
+	if err != nil {
+		return nil, err
+	}
+	return &ethNode{Uri: uri, Client: client, headers: make(chan *types.Header)}, nil
+}
+
+func isHttpUri(uri string) bool {
+	return strings.HasPrefix(uri, "http://") || strings.HasPrefix(uri, "https://")
+}
+
+// firstSubscriptionNode returns the uri of the first node that supports subscriptions (WebSocket or IPC), or of the
+// first node if there is none
+func firstSubscriptionNode(nodes []*ethNode) string {
+	for _, node := range nodes {
+		if !isHttpUri(node.Uri) {
+			return node.Uri
+		}
+	}
+	return nodes[0].Uri
+}
+
+// forwardHeaders sends all headers of the subscription to out. Resubscribes on subscription errors.
+func (n *ethNode) forwardHeaders(ctx context.Context, sub ethereum.Subscription, out chan<- nodeHeader) {
+	n.setActive(true)
+	defer n.setActive(false)
+
+	for {
+		select {
+		case <-ctx.Done():
+			sub.Unsubscribe()
+			return
+		case err := <-sub.Err():
+			logging.Log.Warnw("Subscription error", "node", n.Uri, "error", err)
+			sub.Unsubscribe()
+			n.setActive(false)
+
+			// Blocks already in the backlog are kept and processed after resubscribing
+			sub = n.resubscribeNewHead(ctx)
+			if sub == nil { // shutting down
+				return
+			}
+			n.setActive(true)
+		case header := <-n.headers:
+			select {
+			case out <- nodeHeader{Node: n, Header: header}:
+			case <-ctx.Done():
+			}
+		}
+	}
+}
+
+// resubscribeNewHead retries to subscribe to new headers with exponential backoff. Returns nil if ctx is done.
+func (n *ethNode) resubscribeNewHead(ctx context.Context) ethereum.Subscription {
+	backoff := 1 * time.Second
+	for {
+		logging.Log.Infow("Resubscribing ...", "node", n.Uri, "backoff", backoff)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+
+		sub, err := n.Client.SubscribeNewHead(ctx, n.headers)
+		if err == nil {
+			logging.Log.Infow("Resubscribed to new headers", "node", n.Uri)
+			return sub
+		}
+		logging.Log.Warnw("Resubscribe error", "node", n.Uri, "error", err)
+
+		backoff *= 2
+		if backoff > resubscribeMaxBackoff {
+			backoff = resubscribeMaxBackoff
+		}
+	}
+}
+
+// pollHeaders polls the node for new blocks (for HTTP node URIs, which don't support subscriptions)
+func (n *ethNode) pollHeaders(ctx context.Context, interval time.Duration, out chan<- nodeHeader) {
+	logging.Log.Infow("Polling for new blocks", "node", n.Uri, "interval", interval)
+	var lastHeight int64
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	defer n.setActive(false)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			latestHeight, err := n.Client.BlockNumber(ctx)
+			if err != nil {
+				logging.Log.Warnw("BlockNumber error", "node", n.Uri, "error", err)
+				n.setActive(false)
+				continue
+			}
+			n.setActive(true)
+
+			height := int64(latestHeight)
+			if lastHeight == 0 {
+				lastHeight = height - 1
+			}
+
+			for lastHeight < height {
+				header, err := n.Client.HeaderByNumber(ctx, big.NewInt(lastHeight+1))
+				if err != nil {
+					logging.Log.Warnw("HeaderByNumber error", "node", n.Uri, "error", err)
+					break
+				}
+				lastHeight += 1
+
+				select {
+				case out <- nodeHeader{Node: n, Header: header}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}
+}
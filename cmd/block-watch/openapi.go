@@ -0,0 +1,230 @@
+// OpenAPI 3 document of the webserver (/openapi.json). The response schemas are generated from the Go types, so they
+// follow the JSON encoding of the responses.
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/metachris/flashbots/blockcheck"
+	"github.com/metachris/flashbots/common"
+)
+
+// openApiParam is a query parameter of an endpoint
+type openApiParam struct {
+	Name        string
+	Type        string   // integer, boolean or string
+	Enum        []string // allowed values of string parameters
+	Default     interface{}
+	Description string
+}
+
+// openApiEndpoint is a GET endpoint of the webserver, with the Go type of its JSON response (nil if it's not JSON)
+type openApiEndpoint struct {
+	Path        string
+	Summary     string
+	Params      []openApiParam
+	Response    reflect.Type
+	OrResponse  reflect.Type // other shape of the response (i.e. with format=legacy)
+	ContentType string       // of responses that are not JSON
+	Errors      []int        // status codes of errorResponse, besides 401 with -auth-token
+}
+
+var topParam = openApiParam{Name: "top", Type: "integer", Default: 0, Description: "only the first n entries (0 for all)"}
+
+// openApiEndpoints must list all endpoints of startWebserver
+var openApiEndpoints = []openApiEndpoint{
+	{Path: "/health", Summary: "Liveness probe, always 200 while the webserver is running", Response: reflect.TypeOf(map[string]string{})},
+	{Path: "/ready", Summary: "Readiness probe, 503 until a block was processed and a node subscription is active", Response: reflect.TypeOf(readinessResponse{})},
+	{Path: "/failedTx", Summary: "Recent failed tx of the history, newest first (with format=legacy a bare array of all matching tx, oldest first)", Params: []openApiParam{
+		{Name: "fromBlock", Type: "integer", Description: "only tx from this block"},
+		{Name: "toBlock", Type: "integer", Description: "only tx up to this block (inclusive)"},
+		{Name: "flashbotsOnly", Type: "boolean", Default: false, Description: "only Flashbots tx"},
+		{Name: "from", Type: "string", Description: "only tx of this sender address (case-insensitive)"},
+		{Name: "limit", Type: "integer", Default: failedTxDefaultLimit, Description: "page size (0 for all)"},
+		{Name: "offset", Type: "integer", Default: 0, Description: "number of matching tx to skip"},
+		{Name: "order", Type: "string", Enum: []string{"asc", "desc"}, Default: "desc", Description: "desc for the newest first"},
+		{Name: "format", Type: "string", Enum: []string{"legacy"}, Description: "legacy: bare array of all matching tx, without pagination"},
+	}, Response: reflect.TypeOf(failedTxResponse{}), OrResponse: reflect.TypeOf([]blockcheck.FailedTx{}), Errors: []int{http.StatusBadRequest}},
+	{Path: "/stats", Summary: "Failed Flashbots and other tx in the last 1m, 5m and 1h, and since the start (allTime)", Response: reflect.TypeOf(map[string]failedTxCount{})},
+	{Path: "/byContract", Summary: "Failed tx of the history per recipient contract, the busiest first", Params: []openApiParam{topParam},
+		Response: reflect.TypeOf([]contractFailures{}), Errors: []int{http.StatusBadRequest}},
+	{Path: "/senders", Summary: "Failed tx of the history per sender, the most failures (or gas used) first", Params: []openApiParam{topParam,
+		{Name: "sortBy", Type: "string", Enum: []string{sendersSortByCount, sendersSortByGas}, Default: sendersSortByCount, Description: "sort by the number of failed tx or the gas used"},
+	}, Response: reflect.TypeOf([]senderFailures{}), Errors: []int{http.StatusBadRequest}},
+	{Path: "/stuck", Summary: "Senders with more than one failed tx at the nonce of their latest failed tx", Response: reflect.TypeOf([]StuckSender{})},
+	{Path: "/bundles", Summary: "Likely failed bundles of the history, the newest block first", Response: reflect.TypeOf([]FailedBundle{})},
+	{Path: "/reorgs", Summary: "Recent reorgs of the processed blocks", Response: reflect.TypeOf([]ReorgEvent{})},
+	{Path: "/version", Summary: "Build info", Response: reflect.TypeOf(common.BuildInfo{})},
+	{Path: "/pending", Summary: "Pending 0-gas tx with data (only with -mempool)", Response: reflect.TypeOf([]PendingTx{})},
+	{Path: "/ws", Summary: "WebSocket that pushes every new failed tx as JSON message (FailedTx)", Params: []openApiParam{
+		{Name: "backlog", Type: "boolean", Default: false, Description: "first send the current history"},
+	}},
+	{Path: "/metrics", Summary: "Prometheus metrics", ContentType: "text/plain"},
+	{Path: "/openapi.json", Summary: "This OpenAPI document", ContentType: "application/json"},
+}
+
+// newOpenApiDocument returns the OpenAPI document of openApiEndpoints. Without pending, /pending is left out. With
+// authToken, all endpoints except /health require the bearer token.
+func newOpenApiDocument(pending bool, authToken bool) map[string]interface{} {
+	schemas := make(map[string]interface{})
+	errorSchema := openApiSchemaOf(reflect.TypeOf(errorResponse{}), schemas)
+	openApiSchemaOf(reflect.TypeOf(blockcheck.FailedTx{}), schemas) // the messages of /ws
+
+	paths := make(map[string]interface{})
+	for _, endpoint := range openApiEndpoints {
+		if endpoint.Path == "/pending" && !pending {
+			continue
+		}
+
+		ok := map[string]interface{}{"description": "OK"}
+		if endpoint.Response != nil {
+			schema := openApiSchemaOf(endpoint.Response, schemas)
+			if endpoint.OrResponse != nil {
+				schema = map[string]interface{}{"oneOf": []interface{}{schema, openApiSchemaOf(endpoint.OrResponse, schemas)}}
+			}
+			ok["content"] = map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}}
+		} else if endpoint.ContentType != "" {
+			ok["content"] = map[string]interface{}{endpoint.ContentType: map[string]interface{}{}}
+		}
+		responses := map[string]interface{}{"200": ok}
+		if endpoint.Path == "/ws" {
+			responses = map[string]interface{}{"101": map[string]interface{}{"description": "Switching to the WebSocket protocol"}}
+		}
+		if endpoint.Path == "/ready" {
+			responses["503"] = map[string]interface{}{"description": "Not ready", "content": ok["content"]}
+		}
+		errorContent := map[string]interface{}{"application/json": map[string]interface{}{"schema": errorSchema}}
+		for _, status := range endpoint.Errors {
+			responses[strconv.Itoa(status)] = map[string]interface{}{"description": http.StatusText(status), "content": errorContent}
+		}
+
+		operation := map[string]interface{}{"summary": endpoint.Summary, "responses": responses}
+		if len(endpoint.Params) > 0 {
+			params := make([]interface{}, 0, len(endpoint.Params))
+			for _, param := range endpoint.Params {
+				schema := map[string]interface{}{"type": param.Type}
+				if len(param.Enum) > 0 {
+					schema["enum"] = param.Enum
+				}
+				if param.Default != nil {
+					schema["default"] = param.Default
+				}
+				params = append(params, map[string]interface{}{"name": param.Name, "in": "query", "description": param.Description, "schema": schema})
+			}
+			operation["parameters"] = params
+		}
+		if authToken && endpoint.Path != "/health" {
+			operation["security"] = []interface{}{map[string]interface{}{"bearerAuth": []string{}}}
+			responses["401"] = map[string]interface{}{"description": "Missing or wrong bearer token (-auth-token)", "content": errorContent}
+		}
+		paths[endpoint.Path] = map[string]interface{}{"get": operation}
+	}
+
+	components := map[string]interface{}{"schemas": schemas}
+	if authToken {
+		components["securitySchemes"] = map[string]interface{}{"bearerAuth": map[string]interface{}{"type": "http", "scheme": "bearer"}}
+	}
+	return map[string]interface{}{
+		"openapi":    "3.0.3",
+		"info":       map[string]interface{}{"title": "block-watch", "version": common.BuildVersion()},
+		"paths":      paths,
+		"components": components,
+	}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// openApiSchemaOf returns the schema of values of type t as encoded by encoding/json. Structs are added to schemas
+// (by their type name, capitalized) and referenced.
+func openApiSchemaOf(t reflect.Type, schemas map[string]interface{}) map[string]interface{} {
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		schema := openApiSchemaOf(t.Elem(), schemas)
+		return map[string]interface{}{"allOf": []interface{}{schema}, "nullable": true}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer", "format": openApiIntFormat(t)}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": openApiIntFormat(t), "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": openApiSchemaOf(t.Elem(), schemas)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": openApiSchemaOf(t.Elem(), schemas)}
+	case reflect.Struct:
+		name := openApiSchemaName(t)
+		ref := map[string]interface{}{"$ref": "#/components/schemas/" + name}
+		if _, found := schemas[name]; found {
+			return ref
+		}
+		schemas[name] = nil // placeholder, for recursive types
+		properties := make(map[string]interface{})
+		required := make([]string, 0)
+		addOpenApiProperties(t, properties, &required, schemas)
+		schema := map[string]interface{}{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		schemas[name] = schema
+		return ref
+	}
+	return map[string]interface{}{} // any value
+}
+
+// addOpenApiProperties adds the JSON fields of the struct type t to properties, including the fields of embedded
+// structs. Fields without omitempty are always present, so they are required.
+func addOpenApiProperties(t reflect.Type, properties map[string]interface{}, required *[]string, schemas map[string]interface{}) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options := tag, ""
+		if idx := strings.Index(tag, ","); idx >= 0 {
+			name, options = tag[:idx], tag[idx+1:]
+		}
+
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			addOpenApiProperties(field.Type, properties, required, schemas)
+			continue
+		}
+		if field.PkgPath != "" { // unexported
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = openApiSchemaOf(field.Type, schemas)
+		if !strings.Contains(options, "omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
+
+func openApiSchemaName(t reflect.Type) string {
+	name := []rune(t.Name())
+	name[0] = unicode.ToUpper(name[0])
+	return string(name)
+}
+
+func openApiIntFormat(t reflect.Type) string {
+	if t.Bits() <= 32 {
+		return "int32"
+	}
+	return "int64"
+}
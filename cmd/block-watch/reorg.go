@@ -0,0 +1,144 @@
+// Detection of reorgs in watch mode: processed blocks that were replaced by blocks of another chain (served at /reorgs)
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/metachris/flashbots/logging"
+)
+
+// ReorgEvent is a reorg of the processed blocks from FromBlock to ToBlock, detected when processing NewBlock
+type ReorgEvent struct {
+	FromBlock      int64     `json:"fromBlock"`      // lowest replaced block
+	ToBlock        int64     `json:"toBlock"`        // highest replaced block (the last processed block before the reorg)
+	Depth          int64     `json:"depth"`          // number of replaced blocks
+	ReplacedHashes []string  `json:"replacedHashes"` // hashes of the replaced blocks, lowest first (if still known)
+	NewBlock       int64     `json:"newBlock"`
+	NewHash        string    `json:"newHash"`
+	NewParentHash  string    `json:"newParentHash"`
+	Time           time.Time `json:"time"`
+}
+
+// ReorgHandler is implemented by handlers that need to know about reorgs (in addition to FailedTxHandler)
+type ReorgHandler interface {
+	OnReorg(event ReorgEvent)
+}
+
+// ReorgHistory holds the most recent reorg events (served by the webserver)
+type ReorgHistory struct {
+	lock   sync.RWMutex
+	size   int
+	events []ReorgEvent
+}
+
+func NewReorgHistory(size int) *ReorgHistory {
+	return &ReorgHistory{size: size, events: make([]ReorgEvent, 0, size)}
+}
+
+func (h *ReorgHistory) OnReorg(event ReorgEvent) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.events = append(h.events, event)
+	if h.size > 0 && len(h.events) > h.size {
+		h.events = h.events[len(h.events)-h.size:]
+	}
+}
+
+// List returns a copy of the reorg events, newest first
+func (h *ReorgHistory) List() []ReorgEvent {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	ret := make([]ReorgEvent, len(h.events))
+	for i, event := range h.events {
+		ret[len(ret)-1-i] = event
+	}
+	return ret
+}
+
+// OnReorg marks the failed tx of the replaced blocks as reorged, or removes them (with removeReorged)
+func (h *FailedTxHistory) OnReorg(event ReorgEvent) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	affected := 0
+	txs := h.txs[:0]
+	for _, failedTx := range h.txs {
+		if !failedTx.Reorged && int64(failedTx.Block) >= event.FromBlock && int64(failedTx.Block) <= event.ToBlock {
+			affected += 1
+			if h.removeReorged {
+				continue
+			}
+			failedTx.Reorged = true
+		}
+		txs = append(txs, failedTx)
+	}
+	h.txs = txs
+
+	if affected > 0 {
+		logging.Log.Warnw("Failed tx of reorged blocks", "fromBlock", event.FromBlock, "toBlock", event.ToBlock, "count", affected, "removed", h.removeReorged)
+	}
+}
+
+// checkReorg compares a block that is about to be processed with the recently processed blocks. It's a reorg if a
+// block at the same (or a lower) height was already processed, or if its parent isn't the processed block below it.
+// Returns false for the first block, and for blocks after or within a gap (where the block below is unknown).
+func (w *Watcher) checkReorg(height int64, hash string, parentHash string) (event ReorgEvent, isReorg bool) {
+	w.canonicalLock.Lock()
+	defer w.canonicalLock.Unlock()
+
+	lastHeight := w.lastCanonicalHeight
+	forkHeight := int64(0)
+	if _, found := w.canonicalBlocks[height]; found && height <= lastHeight {
+		forkHeight = height
+	}
+	if parent, found := w.canonicalBlocks[height-1]; found && parent != parentHash {
+		forkHeight = height - 1
+	}
+
+	if forkHeight > 0 {
+		isReorg = true
+		event = ReorgEvent{
+			FromBlock:     forkHeight,
+			ToBlock:       lastHeight,
+			Depth:         lastHeight - forkHeight + 1,
+			NewBlock:      height,
+			NewHash:       hash,
+			NewParentHash: parentHash,
+			Time:          time.Now().UTC(),
+		}
+		for h := forkHeight; h <= lastHeight; h++ {
+			if replaced, found := w.canonicalBlocks[h]; found {
+				event.ReplacedHashes = append(event.ReplacedHashes, replaced)
+			}
+			delete(w.canonicalBlocks, h)
+		}
+	}
+
+	w.canonicalBlocks[height] = hash
+	if isReorg || height > lastHeight { // a late block of a gap doesn't change the head
+		w.lastCanonicalHeight = height
+	}
+	for h := range w.canonicalBlocks { // keep only recent blocks
+		if h < height-100 {
+			delete(w.canonicalBlocks, h)
+		}
+	}
+	return event, isReorg
+}
+
+// handleReorg logs a reorg and passes it to the handlers that implement ReorgHandler
+func (w *Watcher) handleReorg(event ReorgEvent) {
+	metricReorgs.Inc()
+	logging.Log.Warnw("Reorg detected", "fromBlock", event.FromBlock, "toBlock", event.ToBlock, "depth", event.Depth, "replacedHashes", event.ReplacedHashes, "newBlock", event.NewBlock, "newHash", event.NewHash, "newParentHash", event.NewParentHash)
+	for _, handler := range w.Handlers {
+		if reorgHandler, ok := handler.(ReorgHandler); ok {
+			reorgHandler.OnReorg(event)
+		}
+	}
+	if w.Reorgs != nil {
+		w.Reorgs.OnReorg(event)
+	}
+}
@@ -0,0 +1,60 @@
+// Replay: check the blocks stored in the database again, i.e. after the classification logic was improved
+package main
+
+import (
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/metachris/flashbots/blockcheck"
+	"github.com/metachris/flashbots/common"
+	"github.com/metachris/flashbots/logging"
+)
+
+// replayBlocks downloads every block with stored failed tx again, checks it and replaces its stored failed tx. The
+// handlers (notifications, ...) are not called.
+func replayBlocks(client *ethclient.Client, db *FailedTxDatabase, skipFlashbotsApi bool) error {
+	blockNumbers, err := db.BlockNumbers()
+	if err != nil {
+		return err
+	}
+
+	logging.Log.Infow("Replaying stored blocks", "blocks", len(blockNumbers))
+	var numBefore, numAfter, numErrors int
+	for _, blockNumber := range blockNumbers {
+		block, err := common.GetBlockWithTxReceipts(client, blockNumber)
+		if err != nil {
+			logging.Log.Errorw("GetBlockWithTxReceipts error, skipping block", "block", blockNumber, "error", err)
+			numErrors += 1
+			continue
+		}
+
+		check, err := blockcheck.CheckBlock(block, skipFlashbotsApi)
+		if err != nil {
+			logging.Log.Errorw("CheckBlock error, skipping block", "block", blockNumber, "error", err)
+			numErrors += 1
+			continue
+		}
+
+		failedTxs := make([]blockcheck.FailedTx, 0)
+		for _, failedTx := range check.FailedTxList() {
+			failedTxs = append(failedTxs, *failedTx)
+		}
+
+		stored, err := db.CountBlock(blockNumber)
+		if err != nil {
+			return err
+		}
+
+		err = db.ReplaceBlock(blockNumber, failedTxs)
+		if err != nil {
+			return err
+		}
+
+		if stored != len(failedTxs) {
+			logging.Log.Infow("Replayed block with changes", "block", blockNumber, "failedTxBefore", stored, "failedTxAfter", len(failedTxs))
+		}
+		numBefore += stored
+		numAfter += len(failedTxs)
+	}
+
+	logging.Log.Infow("Replay finished", "blocks", len(blockNumbers), "errors", numErrors, "failedTxBefore", numBefore, "failedTxAfter", numAfter)
+	return nil
+}
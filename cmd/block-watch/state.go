@@ -0,0 +1,40 @@
+// State file with the last processed block, to resume watching after a restart
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+)
+
+type WatchState struct {
+	LastProcessedBlock int64 `json:"lastProcessedBlock"`
+}
+
+// loadState reads the state file. Returns an empty state if the file doesn't exist yet.
+func loadState(path string) (state WatchState, err error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	} else if err != nil {
+		return state, err
+	}
+
+	err = json.Unmarshal(data, &state)
+	return state, err
+}
+
+// saveState writes the state to a temporary file and renames it, so the state file is never partially written
+func saveState(path string, state WatchState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	tmpPath := path + ".tmp"
+	err = ioutil.WriteFile(tmpPath, data, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
@@ -0,0 +1,215 @@
+// Failed tx rates over recent time windows, for the /stats endpoint, and failed tx per contract and per sender, for
+// /byContract and /senders
+package main
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/metachris/flashbots/blockcheck"
+)
+
+// Failed tx are counted in one bucket per second, buckets older than the largest window are removed
+const statsBucketSize = time.Second
+
+var statsWindows = []struct {
+	name     string
+	duration time.Duration
+}{
+	{"1m", time.Minute},
+	{"5m", 5 * time.Minute},
+	{"1h", time.Hour},
+}
+
+type failedTxCount struct {
+	Flashbots int `json:"flashbots"`
+	Other     int `json:"other"`
+}
+
+func (c *failedTxCount) add(failedTx blockcheck.FailedTx) {
+	if failedTx.IsFlashbots {
+		c.Flashbots += 1
+	} else {
+		c.Other += 1
+	}
+}
+
+type failedTxStatsBucket struct {
+	start time.Time
+	count failedTxCount
+}
+
+// FailedTxStats counts the failed tx per time bucket (FailedTxHandler)
+type FailedTxStats struct {
+	lock    sync.Mutex
+	buckets []failedTxStatsBucket // oldest first
+	allTime failedTxCount
+}
+
+func NewFailedTxStats() *FailedTxStats {
+	return &FailedTxStats{}
+}
+
+func (s *FailedTxStats) OnFailedTxs(block *types.Block, failedTxs []blockcheck.FailedTx) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+	bucketStart := now.Truncate(statsBucketSize)
+	if len(s.buckets) == 0 || !s.buckets[len(s.buckets)-1].start.Equal(bucketStart) {
+		s.buckets = append(s.buckets, failedTxStatsBucket{start: bucketStart})
+	}
+
+	bucket := &s.buckets[len(s.buckets)-1]
+	for _, failedTx := range failedTxs {
+		if !failedTx.IsFailed() {
+			continue
+		}
+		bucket.count.add(failedTx)
+		s.allTime.add(failedTx)
+	}
+	s.prune(now)
+}
+
+// prune removes the buckets that are older than the largest window
+func (s *FailedTxStats) prune(now time.Time) {
+	maxWindow := statsWindows[len(statsWindows)-1].duration
+	i := 0
+	for i < len(s.buckets) && now.Sub(s.buckets[i].start) > maxWindow {
+		i++
+	}
+	s.buckets = s.buckets[i:]
+}
+
+// Get returns the failed tx count of every window, and of all time (key "allTime")
+func (s *FailedTxStats) Get() map[string]failedTxCount {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+	s.prune(now)
+
+	ret := map[string]failedTxCount{"allTime": s.allTime}
+	for _, window := range statsWindows {
+		var count failedTxCount
+		for _, bucket := range s.buckets {
+			if now.Sub(bucket.start) <= window.duration {
+				count.Flashbots += bucket.count.Flashbots
+				count.Other += bucket.count.Other
+			}
+		}
+		ret[window.name] = count
+	}
+	return ret
+}
+
+// contractFailures is the number of failed tx to a recipient contract (for /byContract)
+type contractFailures struct {
+	Contract string `json:"contract"`
+	Total    int    `json:"total"`
+	failedTxCount
+}
+
+// countFailuresByContract counts the failed tx of history per recipient, the busiest first (only the top ones if top is
+// not 0). Successful tx and contract creations are not counted.
+func countFailuresByContract(history []blockcheck.FailedTx, top int) []contractFailures {
+	counts := make(map[string]*contractFailures) // recipient (lowercase) -> count
+	for _, failedTx := range history {
+		if !failedTx.IsFailed() || failedTx.To == "" {
+			continue
+		}
+
+		contract := strings.ToLower(failedTx.To)
+		count, found := counts[contract]
+		if !found {
+			count = &contractFailures{Contract: failedTx.To}
+			counts[contract] = count
+		}
+		count.Total += 1
+		count.add(failedTx)
+	}
+
+	ret := make([]contractFailures, 0, len(counts))
+	for _, count := range counts {
+		ret = append(ret, *count)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Total != ret[j].Total {
+			return ret[i].Total > ret[j].Total
+		}
+		return strings.ToLower(ret[i].Contract) < strings.ToLower(ret[j].Contract)
+	})
+
+	if top > 0 && len(ret) > top {
+		ret = ret[:top]
+	}
+	return ret
+}
+
+// Orders of /senders (sortBy)
+const (
+	sendersSortByCount = "count"
+	sendersSortByGas   = "gas"
+)
+
+// senderFailures are the failed tx of a sender (for /senders)
+type senderFailures struct {
+	Sender string `json:"sender"`
+	Total  int    `json:"total"`
+	failedTxCount
+	FirstBlock uint64 `json:"firstBlock"`
+	LastBlock  uint64 `json:"lastBlock"`
+	GasUsed    uint64 `json:"gasUsed"` // of all failed tx of the sender
+}
+
+// countFailuresBySender counts the failed tx of history per sender, the most failures (sortBy count) or the most gas
+// used (sortBy gas) first, and only the top ones if top is not 0. Successful tx are not counted.
+func countFailuresBySender(history []blockcheck.FailedTx, top int, sortBy string) []senderFailures {
+	counts := make(map[string]*senderFailures) // sender (lowercase) -> count
+	for _, failedTx := range history {
+		if !failedTx.IsFailed() {
+			continue
+		}
+
+		sender := strings.ToLower(failedTx.From)
+		count, found := counts[sender]
+		if !found {
+			count = &senderFailures{Sender: failedTx.From, FirstBlock: failedTx.Block, LastBlock: failedTx.Block}
+			counts[sender] = count
+		}
+		count.Total += 1
+		count.add(failedTx)
+		count.GasUsed += failedTx.GasUsed
+		if failedTx.Block < count.FirstBlock {
+			count.FirstBlock = failedTx.Block
+		}
+		if failedTx.Block > count.LastBlock {
+			count.LastBlock = failedTx.Block
+		}
+	}
+
+	ret := make([]senderFailures, 0, len(counts))
+	for _, count := range counts {
+		ret = append(ret, *count)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if sortBy == sendersSortByGas && ret[i].GasUsed != ret[j].GasUsed {
+			return ret[i].GasUsed > ret[j].GasUsed
+		}
+		if ret[i].Total != ret[j].Total {
+			return ret[i].Total > ret[j].Total
+		}
+		if ret[i].GasUsed != ret[j].GasUsed {
+			return ret[i].GasUsed > ret[j].GasUsed
+		}
+		return strings.ToLower(ret[i].Sender) < strings.ToLower(ret[j].Sender)
+	})
+
+	if top > 0 && len(ret) > top {
+		ret = ret[:top]
+	}
+	return ret
+}
@@ -0,0 +1,57 @@
+// Detection of stuck searchers: senders whose latest failed tx share a nonce across multiple blocks (served at /stuck)
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/metachris/flashbots/blockcheck"
+)
+
+// StuckSender is a sender with more than one failed tx at the nonce of its most recent failed tx. A nonce can be mined
+// only once per chain, so these are resubmissions that were mined in competing (reorged) blocks.
+type StuckSender struct {
+	From        string   `json:"from"`
+	Nonce       uint64   `json:"nonce"`
+	Blocks      []uint64 `json:"blocks"`   // heights of the blocks with a failed tx of the sender at Nonce, oldest first
+	Failures    int      `json:"failures"` // failed tx of the sender at Nonce (one per block they were mined in)
+	LatestBlock uint64   `json:"latestBlock"`
+}
+
+// findStuckSenders returns the stuck senders of history (oldest entry first), the most recently failed sender first.
+// Entries restored from a database of an older version (without Status) are skipped, because they have no nonce.
+func findStuckSenders(history []blockcheck.FailedTx) []StuckSender {
+	latest := make(map[string]*StuckSender) // sender (lowercase) -> failures at the nonce of its latest failed tx
+	for _, failedTx := range history {
+		if failedTx.Status == "" || !failedTx.IsFailed() {
+			continue
+		}
+
+		sender := strings.ToLower(failedTx.From)
+		stuck, found := latest[sender]
+		if !found || stuck.Nonce != failedTx.Nonce {
+			stuck = &StuckSender{From: failedTx.From, Nonce: failedTx.Nonce}
+			latest[sender] = stuck
+		}
+
+		stuck.Failures += 1
+		if len(stuck.Blocks) == 0 || stuck.Blocks[len(stuck.Blocks)-1] != failedTx.Block {
+			stuck.Blocks = append(stuck.Blocks, failedTx.Block)
+		}
+		stuck.LatestBlock = failedTx.Block
+	}
+
+	ret := make([]StuckSender, 0)
+	for _, stuck := range latest {
+		if stuck.Failures > 1 {
+			ret = append(ret, *stuck)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].LatestBlock != ret[j].LatestBlock {
+			return ret[i].LatestBlock > ret[j].LatestBlock
+		}
+		return ret[i].From < ret[j].From
+	})
+	return ret
+}
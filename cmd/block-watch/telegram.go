@@ -0,0 +1,152 @@
+// Telegram bot notifications
+// https://core.telegram.org/bots/api#sendmessage
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/metachris/flashbots/blockcheck"
+	"github.com/metachris/flashbots/common"
+	"github.com/metachris/flashbots/logging"
+)
+
+type TelegramSendMessagePayload struct {
+	ChatId                string `json:"chat_id"`
+	Text                  string `json:"text"`
+	ParseMode             string `json:"parse_mode"`
+	DisableWebPagePreview bool   `json:"disable_web_page_preview"`
+}
+
+var telegramClient = &http.Client{Timeout: 10 * time.Second}
+
+// Maximum length of a Telegram message (of the text without the Markdown, so the Markdown is split a bit earlier than
+// needed)
+const telegramMaxMessageLength = 4096
+
+const telegramQueueSize = 100
+
+// telegramHandler sends the failed Flashbots tx of a block to a Telegram chat. The blocks are queued, the queue is
+// drained by a single goroutine so a slow Telegram API never blocks block processing.
+type telegramHandler struct {
+	token  string
+	chatId string
+	queue  chan discordMessage // the failed tx of a block
+}
+
+// NewTelegramHandler starts the goroutine that sends the queued failed tx to the chat
+func NewTelegramHandler(token string, chatId string) *telegramHandler {
+	h := &telegramHandler{token: token, chatId: chatId, queue: make(chan discordMessage, telegramQueueSize)}
+
+	go func() {
+		for msg := range h.queue {
+			err := h.SendFailedTx(msg.blockNumber, msg.failedTxs)
+			if err != nil {
+				logging.Log.Errorw("Error sending failed tx to Telegram", "block", msg.blockNumber, "error", err)
+			}
+		}
+	}()
+	return h
+}
+
+// OnFailedTxs adds the failed Flashbots tx of the block to the Telegram queue, or drops them if the queue is full
+func (h *telegramHandler) OnFailedTxs(block *types.Block, failedTxs []blockcheck.FailedTx) {
+	flashbotsFailedTxs := make([]*blockcheck.FailedTx, 0)
+	for _, failedTx := range failedTxs {
+		if failedTx.IsFailed() && failedTx.IsFlashbots {
+			failedTx := failedTx // a copy, the queue outlives the call
+			flashbotsFailedTxs = append(flashbotsFailedTxs, &failedTx)
+		}
+	}
+
+	if len(flashbotsFailedTxs) > 0 {
+		select {
+		case h.queue <- discordMessage{blockNumber: block.Number().Int64(), failedTxs: flashbotsFailedTxs}:
+		default:
+			logging.Log.Warnw("Telegram queue is full, dropping failed tx", "block", block.Number(), "count", len(flashbotsFailedTxs))
+		}
+	}
+}
+
+// SendFailedTx sends all failed transactions of a block in one message, or if it's too long in several messages (split
+// between the transactions, each with the block line)
+func (h *telegramHandler) SendFailedTx(blockNumber int64, failedTxs []*blockcheck.FailedTx) error {
+	header := fmt.Sprintf("Block [%d](%s): %d failed Flashbots tx\n", blockNumber, common.ExplorerBlockUrl(blockNumber), len(failedTxs))
+	entries := make([]string, len(failedTxs))
+	for i, tx := range failedTxs {
+		to := "contract creation"
+		if tx.To != "" {
+			to = fmt.Sprintf("[%s](%s)", tx.To, common.ExplorerAddressUrl(tx.To))
+		}
+		entries[i] = fmt.Sprintf("- [%s](%s)\n  from [%s](%s)\n  to %s\n", tx.Hash, common.ExplorerTxUrl(tx.Hash), tx.From, common.ExplorerAddressUrl(tx.From), to)
+	}
+
+	for _, msg := range splitMessage(header, entries, telegramMaxMessageLength) {
+		if err := h.Send(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitMessage joins the entries into messages of at most maxLength bytes that start with the header, split between
+// entries. An entry that doesn't fit into a message by itself is cut.
+func splitMessage(header string, entries []string, maxLength int) []string {
+	ret := make([]string, 0, 1)
+	msg := header
+	for _, entry := range entries {
+		if len(msg)+len(entry) > maxLength && msg != header {
+			ret = append(ret, msg)
+			msg = header
+		}
+		if len(msg)+len(entry) > maxLength {
+			entry = entry[:maxLength-len(msg)]
+		}
+		msg += entry
+	}
+	return append(ret, msg)
+}
+
+// Send sends a Markdown message to the chat. Messages longer than telegramMaxMessageLength are split between lines.
+func (h *telegramHandler) Send(msg string) error {
+	if len(msg) > telegramMaxMessageLength {
+		for _, part := range splitMessage("", strings.SplitAfter(msg, "\n"), telegramMaxMessageLength) {
+			if err := h.send(part); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return h.send(msg)
+}
+
+func (h *telegramHandler) send(msg string) error {
+	payload := TelegramSendMessagePayload{ChatId: h.chatId, Text: msg, ParseMode: "Markdown", DisableWebPagePreview: true}
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", h.token)
+	res, err := telegramClient.Post(url, "application/json", bytes.NewBuffer(payloadBytes))
+	if err != nil {
+		if urlErr, ok := err.(*neturl.Error); ok { // don't log the URL, it contains the bot token
+			return fmt.Errorf("telegram request error: %w", urlErr.Err)
+		}
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		bodyBytes, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("telegram response status: %s, body: %s", res.Status, string(bodyBytes))
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitMessage(t *testing.T) {
+	entries := []string{"- 1\n", "- 2\n", "- 3\n"}
+
+	msgs := splitMessage("Block 1\n", entries, 100)
+	if len(msgs) != 1 || msgs[0] != "Block 1\n- 1\n- 2\n- 3\n" {
+		t.Error("Wrong messages:", msgs)
+	}
+
+	// Split between entries, every message starts with the header
+	msgs = splitMessage("Block 1\n", entries, 16)
+	expected := []string{"Block 1\n- 1\n- 2\n", "Block 1\n- 3\n"}
+	if strings.Join(msgs, "|") != strings.Join(expected, "|") {
+		t.Error("Wrong messages:", msgs, "wanted:", expected)
+	}
+
+	// An entry that is too long by itself is cut
+	msgs = splitMessage("Block 1\n", []string{"- 1\n", strings.Repeat("x", 20)}, 16)
+	expected = []string{"Block 1\n- 1\n", "Block 1\nxxxxxxxx"}
+	if strings.Join(msgs, "|") != strings.Join(expected, "|") {
+		t.Error("Wrong messages:", msgs, "wanted:", expected)
+	}
+	for _, msg := range msgs {
+		if len(msg) > 16 {
+			t.Error("Wrong message length:", len(msg), "wanted at most:", 16)
+		}
+	}
+}
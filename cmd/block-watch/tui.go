@@ -0,0 +1,139 @@
+// Terminal UI for watch mode (-tui): a live, scrollable table of recent failed tx, with the log below
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/gdamore/tcell/v2"
+	"github.com/metachris/flashbots/blockcheck"
+	"github.com/rivo/tview"
+)
+
+var tuiColumns = []string{"Block", "Hash", "From", "To", "Flashbots"}
+
+const tuiMaxLogLines = 1000
+
+// TUI shows the failed tx (FailedTxHandler) and the log output (io.Writer)
+type TUI struct {
+	app     *tview.Application
+	table   *tview.Table
+	logView *tview.TextView
+	logOut  io.Writer
+	maxRows int // maximum number of failed tx in the table (0 means unbounded)
+
+	lock    sync.Mutex
+	running bool       // after the TUI was stopped, the log is written to stdout
+	rows    [][]string // newest first
+	colors  []tcell.Color
+}
+
+func NewTUI(maxRows int) *TUI {
+	t := &TUI{app: tview.NewApplication(), maxRows: maxRows}
+
+	t.table = tview.NewTable().SetFixed(1, 0).SetSelectable(true, false)
+	t.table.SetBorder(true).SetTitle(" Failed tx (newest first, q to quit) ")
+	for i, column := range tuiColumns {
+		t.table.SetCell(0, i, tview.NewTableCell(column).SetTextColor(tcell.ColorYellow).SetSelectable(false))
+	}
+
+	t.logView = tview.NewTextView().SetDynamicColors(true).SetMaxLines(tuiMaxLogLines).ScrollToEnd()
+	t.logView.SetBorder(true).SetTitle(" Log ")
+	t.logView.SetChangedFunc(func() { t.app.Draw() })
+	t.logOut = tview.ANSIWriter(t.logView)
+
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).AddItem(t.table, 0, 2, true).AddItem(t.logView, 0, 1, false)
+	t.app.SetRoot(layout, true).SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Rune() == 'q' {
+			t.app.Stop()
+			return nil
+		}
+		return event
+	})
+	return t
+}
+
+// Run shows the TUI until ctx is done or the user quits (q or Ctrl-C), then calls stop
+func (t *TUI) Run(ctx context.Context, stop func()) {
+	t.lock.Lock()
+	t.running = true
+	t.lock.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		t.app.Stop()
+	}()
+
+	err := t.app.Run()
+
+	t.lock.Lock()
+	t.running = false
+	t.lock.Unlock()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "TUI error:", err)
+	}
+	stop()
+}
+
+// Write adds log output to the log view, or writes it to stdout if the TUI isn't running
+func (t *TUI) Write(p []byte) (n int, err error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if !t.running {
+		return os.Stdout.Write(p)
+	}
+	return t.logOut.Write(p)
+}
+
+// OnFailedTxs adds the failed tx at the top of the table and removes the oldest rows that exceed maxRows
+func (t *TUI) OnFailedTxs(block *types.Block, failedTxs []blockcheck.FailedTx) {
+	t.lock.Lock()
+	for _, failedTx := range failedTxs {
+		to := failedTx.To
+		if to == "" {
+			to = "contract creation"
+		}
+
+		color := tcell.ColorWhite
+		if failedTx.IsFlashbots {
+			color = tcell.ColorRed
+		}
+
+		row := []string{strconv.FormatUint(failedTx.Block, 10), failedTx.Hash, failedTx.From, to, strconv.FormatBool(failedTx.IsFlashbots)}
+		t.rows = append([][]string{row}, t.rows...)
+		t.colors = append([]tcell.Color{color}, t.colors...)
+	}
+	if t.maxRows > 0 && len(t.rows) > t.maxRows {
+		t.rows = t.rows[:t.maxRows]
+		t.colors = t.colors[:t.maxRows]
+	}
+	running := t.running
+	t.lock.Unlock()
+
+	// QueueUpdateDraw blocks until the update is done, which never happens if the TUI is stopped in the meantime. The
+	// update redraws all rows, so the order of the updates doesn't matter.
+	if running {
+		go t.app.QueueUpdateDraw(t.updateTable)
+	}
+}
+
+// updateTable sets the rows of the table below the header (in the TUI goroutine)
+func (t *TUI) updateTable() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for t.table.GetRowCount() > len(t.rows)+1 {
+		t.table.RemoveRow(t.table.GetRowCount() - 1)
+	}
+	for i, row := range t.rows {
+		for j, value := range row {
+			t.table.SetCell(i+1, j, tview.NewTableCell(value).SetTextColor(t.colors[i]))
+		}
+	}
+}
@@ -0,0 +1,586 @@
+// Watcher processes new blocks of the eth nodes and delivers the failed tx to its handlers
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/metachris/flashbots/api"
+	"github.com/metachris/flashbots/blockcheck"
+	"github.com/metachris/flashbots/common"
+	"github.com/metachris/flashbots/logging"
+	"github.com/metachris/go-ethutils/blockswithtx"
+	"github.com/metachris/go-ethutils/utils"
+)
+
+// Handling of the oldest blocks when the backlog has more than Watcher.MaxBacklog blocks (-backlog-policy)
+const (
+	backlogPolicyProcess = "process" // process without the Flashbots API data, the failed tx get FlashbotsUnknown
+	backlogPolicyDrop    = "drop"    // drop the blocks (with a warning)
+)
+
+type Watcher struct {
+	Handlers         []FailedTxHandler
+	SkipFlashbotsApi bool          // if there is no Flashbots API for the chain
+	MaxBackfill      int64         // maximum number of missed blocks to backfill when a new header is more than one block ahead
+	StateFile        string        // last processed block is saved here (optional)
+	BacklogWarnDepth int           // warn when more blocks are waiting for the Flashbots API (0 disables it)
+	Confirmations    int64         // a block is processed when the head is this many blocks beyond it
+	MaxBacklog       int           // maximum number of blocks in the backlog, the oldest blocks beyond it are handled by BacklogPolicy (0 disables it)
+	BacklogPolicy    string        // backlogPolicyProcess or backlogPolicyDrop
+	Out              io.Writer     // block errors and summaries are printed here (default: stdout)
+	Reorgs           *ReorgHistory // detected reorgs are added here (optional)
+
+	// New blocks that the node doesn't have yet (or without receipts) are retried after NotFoundRetryDelay, up to
+	// NotFoundRetries times
+	NotFoundRetries    int
+	NotFoundRetryDelay time.Duration
+
+	// Backlog of new blocks that are not yet present in the mev-blocks API (it has ~5 blocks delay), and the hashes of
+	// the blocks that were taken from the backlog for processing (so no block is checked twice). Access with backlogLock.
+	blockBacklog    map[int64]*blockswithtx.BlockWithTxReceipts
+	processedBlocks map[ethcommon.Hash]int64 // block hash -> height
+	backlogLock     sync.Mutex
+	backlogWarned   bool // if BacklogWarnDepth was exceeded, to warn only once until the backlog recovers
+
+	// Hashes of the recently processed blocks of the current chain, to detect reorgs. Access with canonicalLock.
+	canonicalBlocks     map[int64]string // height -> block hash
+	lastCanonicalHeight int64
+	canonicalLock       sync.Mutex
+
+	errorCountSerious    int
+	errorCountNonSerious int
+
+	dailyErrorSummary  blockcheck.ErrorSummary
+	weeklyErrorSummary blockcheck.ErrorSummary
+}
+
+func NewWatcher(handlers []FailedTxHandler) *Watcher {
+	return &Watcher{
+		Handlers:           handlers,
+		MaxBackfill:        100,
+		Out:                os.Stdout,
+		NotFoundRetries:    3,
+		NotFoundRetryDelay: 5 * time.Second,
+		blockBacklog:       make(map[int64]*blockswithtx.BlockWithTxReceipts),
+		processedBlocks:    make(map[ethcommon.Hash]int64),
+		canonicalBlocks:    make(map[int64]string),
+		dailyErrorSummary:  blockcheck.NewErrorSummary(),
+		weeklyErrorSummary: blockcheck.NewErrorSummary(),
+	}
+}
+
+// Watch processes new blocks from all nodes. Blocks are deduplicated by hash, so each block is processed only once,
+// no matter which node delivered it first.
+// Blocks after startHeight that are missed before the first new header are backfilled (if not 0).
+func (w *Watcher) Watch(ctx context.Context, nodes []*ethNode, pollInterval time.Duration, startHeight int64) {
+	headers := make(chan nodeHeader)
+	retries := make(chan headerRetry)
+
+	// Subscribe to all nodes before starting (fails if the initial subscription fails)
+	for _, node := range nodes {
+		if isHttpUri(node.Uri) { // HTTP doesn't support subscriptions
+			go node.pollHeaders(ctx, pollInterval, headers)
+		} else {
+			sub, err := node.Client.SubscribeNewHead(ctx, node.headers)
+			if err != nil {
+				logging.Exitw(logging.ExitCodeEthNode, "Subscription error", "node", node.Uri, "error", err)
+			}
+			go node.forwardHeaders(ctx, sub, headers)
+		}
+	}
+
+	seenBlocks := make(map[ethcommon.Hash]int64) // block hash -> height
+	lastHeight := startHeight                    // highest block height received so far
+	for {
+		select {
+		case <-ctx.Done():
+			w.flushBlockBacklog()
+			return
+		case h := <-headers:
+			height := h.Header.Number.Int64()
+			if _, seen := seenBlocks[h.Header.Hash()]; seen {
+				continue
+			}
+
+			seenBlocks[h.Header.Hash()] = height
+
+			// Blocks between the last and this header were missed (i.e. the subscription was down), queue them too
+			if lastHeight > 0 && height > lastHeight+1 {
+				for _, b := range w.backfillBlocks(h.Node.Client, lastHeight+1, height-1) {
+					seenBlocks[b.Block.Hash()] = b.Block.Number().Int64()
+				}
+			}
+			if height > lastHeight {
+				lastHeight = height
+			}
+
+			for hash, seenHeight := range seenBlocks { // keep only recent blocks
+				if seenHeight < height-100 {
+					delete(seenBlocks, hash)
+				}
+			}
+
+			w.processHeaderWithRetry(ctx, h, 1, retries)
+		case r := <-retries:
+			w.processHeaderWithRetry(ctx, r.header, r.attempt, retries)
+		}
+	}
+}
+
+// errBlockReceiptsNotAvailable is returned for a block with transactions but no receipts (the node hasn't indexed it yet)
+var errBlockReceiptsNotAvailable = errors.New("block receipts not yet available")
+
+type headerRetry struct {
+	header  nodeHeader
+	attempt int
+}
+
+// processHeaderWithRetry processes a new header. If the node doesn't have the block yet (a propagation race), the
+// header is sent to retries after NotFoundRetryDelay.
+func (w *Watcher) processHeaderWithRetry(ctx context.Context, h nodeHeader, attempt int, retries chan<- headerRetry) {
+	height := h.Header.Number.Int64()
+	err := w.processNewHeader(h.Node.Client, height)
+	if err == nil {
+		return
+	}
+
+	isNotAvailable := errors.Is(err, ethereum.NotFound) || errors.Is(err, errBlockReceiptsNotAvailable)
+	if !isNotAvailable || attempt > w.NotFoundRetries {
+		logging.Log.Errorw("GetBlockWithTxReceipts error, skipping block", "block", height, "attempt", attempt, "error", err)
+		return
+	}
+
+	logging.Log.Warnw("Block not yet available, retrying later", "block", height, "attempt", attempt, "delay", w.NotFoundRetryDelay)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-time.After(w.NotFoundRetryDelay):
+			select {
+			case retries <- headerRetry{header: h, attempt: attempt + 1}:
+			case <-ctx.Done():
+			}
+		}
+	}()
+}
+
+// backfillBlocks downloads the blocks from startHeight to endHeight and adds them to the backlog. At most
+// MaxBackfill (the most recent ones) are added, blocks before are skipped.
+func (w *Watcher) backfillBlocks(client *ethclient.Client, startHeight int64, endHeight int64) (blocks []*blockswithtx.BlockWithTxReceipts) {
+	if endHeight-startHeight+1 > w.MaxBackfill {
+		newStartHeight := endHeight - w.MaxBackfill + 1
+		logging.Log.Warnw("Too many missed blocks, skipping the oldest", "fromBlock", startHeight, "toBlock", newStartHeight-1, "maxBackfill", w.MaxBackfill)
+		startHeight = newStartHeight
+	}
+
+	if startHeight > endHeight {
+		return blocks
+	}
+
+	logging.Log.Infow("Backfilling missed blocks", "fromBlock", startHeight, "toBlock", endHeight)
+	for height := startHeight; height <= endHeight; height++ {
+		b, err := common.GetBlockWithTxReceipts(client, height)
+		if err != nil {
+			logging.Log.Errorw("GetBlockWithTxReceipts error, skipping block", "block", height, "error", err)
+			continue
+		}
+		w.addToBacklog(b)
+		blocks = append(blocks, b)
+	}
+	return blocks
+}
+
+// processNewHeader downloads the block with tx-receipts, adds it to the backlog and processes the backlog
+func (w *Watcher) processNewHeader(client *ethclient.Client, height int64) error {
+	b, err := common.GetBlockWithTxReceipts(client, height)
+	if err != nil {
+		return err
+	}
+	if len(b.Block.Transactions()) > 0 && len(b.TxReceipts) == 0 {
+		return errBlockReceiptsNotAvailable
+	}
+
+	if !noBlockInfo {
+		logging.Log.Infow("Queueing new block", "block", height, "hash", b.Block.Hash().Hex())
+	}
+
+	if height > atomic.LoadInt64(&latestNodeBlock) {
+		atomic.StoreInt64(&latestNodeBlock, height)
+	}
+
+	// Add to backlog, because it can only be processed when the Flashbots API has caught up
+	w.addToBacklog(b)
+	w.processReadyBlocks()
+	return nil
+}
+
+// addToBacklog adds the block to the backlog, unless it was already processed. Replaces a block with the same height.
+func (w *Watcher) addToBacklog(b *blockswithtx.BlockWithTxReceipts) {
+	w.backlogLock.Lock()
+	defer w.backlogLock.Unlock()
+
+	if _, processed := w.processedBlocks[b.Block.Hash()]; processed {
+		logging.Log.Debugw("Block already processed, not adding to backlog", "block", b.Block.Number(), "hash", b.Block.Hash().Hex())
+		return
+	}
+	w.blockBacklog[b.Block.Number().Int64()] = b
+}
+
+// takeFromBacklog removes and returns the lowest block of the backlog at or below maxHeight, and marks it as
+// processed. Returns nil if there is none.
+func (w *Watcher) takeFromBacklog(maxHeight int64) *blockswithtx.BlockWithTxReceipts {
+	w.backlogLock.Lock()
+	defer w.backlogLock.Unlock()
+
+	heights := make([]int64, 0, len(w.blockBacklog))
+	for height := range w.blockBacklog {
+		if height <= maxHeight {
+			heights = append(heights, height)
+		}
+	}
+	if len(heights) == 0 {
+		return nil
+	}
+	sort.Slice(heights, func(i, j int) bool { return heights[i] < heights[j] })
+
+	b := w.blockBacklog[heights[0]]
+	delete(w.blockBacklog, heights[0])
+	w.processedBlocks[b.Block.Hash()] = heights[0]
+	for hash, processedHeight := range w.processedBlocks { // keep only recent blocks
+		if processedHeight < heights[0]-100 {
+			delete(w.processedBlocks, hash)
+		}
+	}
+	return b
+}
+
+// takeBacklogOverflow removes and returns the lowest block of the backlog if it has more than MaxBacklog blocks, and
+// marks it as processed (see takeFromBacklog). Returns nil if the backlog isn't full.
+func (w *Watcher) takeBacklogOverflow() *blockswithtx.BlockWithTxReceipts {
+	w.backlogLock.Lock()
+	overflow := len(w.blockBacklog) > w.MaxBacklog
+	w.backlogLock.Unlock()
+	if !overflow {
+		return nil
+	}
+	return w.takeFromBacklog(math.MaxInt64)
+}
+
+// returnToBacklog puts a block that couldn't be processed back into the backlog, to be processed again later (unless
+// there is a new block with the same height)
+func (w *Watcher) returnToBacklog(b *blockswithtx.BlockWithTxReceipts) {
+	w.backlogLock.Lock()
+	defer w.backlogLock.Unlock()
+
+	delete(w.processedBlocks, b.Block.Hash())
+	if _, found := w.blockBacklog[b.Block.Number().Int64()]; !found {
+		w.blockBacklog[b.Block.Number().Int64()] = b
+	}
+}
+
+// backlogHeights returns the heights of the blocks in the backlog
+func (w *Watcher) backlogHeights() (heights []int64) {
+	w.backlogLock.Lock()
+	defer w.backlogLock.Unlock()
+
+	for height := range w.blockBacklog {
+		heights = append(heights, height)
+	}
+	sort.Slice(heights, func(i, j int) bool { return heights[i] < heights[j] })
+	return heights
+}
+
+// confirmedHeight returns the highest block with Confirmations blocks beyond it, of the latest node block or the
+// highest block in the backlog (i.e. after a backfill)
+func (w *Watcher) confirmedHeight() int64 {
+	if w.Confirmations == 0 {
+		return math.MaxInt64
+	}
+
+	head := atomic.LoadInt64(&latestNodeBlock)
+	if heights := w.backlogHeights(); len(heights) > 0 && heights[len(heights)-1] > head {
+		head = heights[len(heights)-1]
+	}
+	return head - w.Confirmations
+}
+
+// processReadyBlocks processes the blocks in the backlog that are confirmed and that the Flashbots API has caught up with
+func (w *Watcher) processReadyBlocks() {
+	if w.SkipFlashbotsApi {
+		w.processBlockBacklog(w.confirmedHeight())
+		return
+	}
+
+	defer w.checkBacklogDepth()
+	defer w.enforceMaxBacklog() // also if the Flashbots API is down
+
+	// Query flashbots API to get latest block it has processed (same request for every header, so it can be cached)
+	opts := api.GetBlocksOptions{Limit: 1}
+	flashbotsResponse, err := api.GetBlocks(&opts)
+	if errors.Is(err, api.ErrTimeout) { // the backlog is processed again on the next header
+		logging.Log.Warnw("Flashbots API timeout", "error", err)
+		return
+	} else if err != nil {
+		logging.Log.Errorw("Flashbots API error", "error", err)
+		return
+	}
+
+	atomic.StoreInt64(&latestFlashbotsBlock, flashbotsResponse.LatestBlockNumber)
+	metricFlashbotsApiLag.Set(float64(flashbotsApiLag()))
+	maxHeight := flashbotsResponse.LatestBlockNumber
+	if confirmedHeight := w.confirmedHeight(); confirmedHeight < maxHeight {
+		maxHeight = confirmedHeight
+	}
+	w.processBlockBacklog(maxHeight)
+}
+
+// flashbotsApiLag returns the number of blocks the Flashbots API is behind the latest new block of the nodes
+func flashbotsApiLag() int64 {
+	lag := atomic.LoadInt64(&latestNodeBlock) - atomic.LoadInt64(&latestFlashbotsBlock)
+	if lag < 0 {
+		return 0
+	}
+	return lag
+}
+
+// checkBacklogDepth warns once when more than BacklogWarnDepth blocks are waiting for the Flashbots API (failed tx
+// of these blocks are reported late, or missed if the blocks are replaced), and again after the backlog recovered.
+// The blocks waiting for confirmations don't count.
+func (w *Watcher) checkBacklogDepth() {
+	depth := len(w.backlogHeights())
+	metricBlockBacklogSize.Set(float64(depth))
+	if w.BacklogWarnDepth == 0 {
+		return
+	}
+
+	depth -= int(w.Confirmations)
+	if depth > w.BacklogWarnDepth && !w.backlogWarned {
+		w.backlogWarned = true
+		logging.Log.Warnw("Block backlog is growing, the Flashbots API is behind", "backlog", depth, "maxDepth", w.BacklogWarnDepth, "flashbotsLatestBlock", atomic.LoadInt64(&latestFlashbotsBlock), "lag", flashbotsApiLag())
+	} else if depth <= w.BacklogWarnDepth && w.backlogWarned {
+		w.backlogWarned = false
+		logging.Log.Infow("Block backlog recovered", "backlog", depth, "lag", flashbotsApiLag())
+	}
+}
+
+// enforceMaxBacklog takes the oldest blocks from the backlog while it has more than MaxBacklog blocks, and processes
+// them without the Flashbots API data (backlogPolicyProcess) or drops them (backlogPolicyDrop)
+func (w *Watcher) enforceMaxBacklog() {
+	if w.MaxBacklog == 0 {
+		return
+	}
+
+	for {
+		b := w.takeBacklogOverflow()
+		if b == nil {
+			return
+		}
+
+		height := b.Block.Number().Int64()
+		metricBacklogOverflow.WithLabelValues(w.BacklogPolicy).Inc()
+		if w.BacklogPolicy == backlogPolicyDrop {
+			logging.Log.Warnw("Block backlog is full, dropping the oldest block", "block", height, "hash", b.Block.Hash().Hex(), "maxBacklog", w.MaxBacklog, "lag", flashbotsApiLag())
+			continue
+		}
+
+		logging.Log.Warnw("Block backlog is full, processing the oldest block without the Flashbots API", "block", height, "hash", b.Block.Hash().Hex(), "maxBacklog", w.MaxBacklog, "lag", flashbotsApiLag())
+		if !w.processBlock(b, true) { // returned to the backlog, retried with the next header
+			return
+		}
+	}
+}
+
+// flushBlockBacklog processes all blocks in the backlog that the Flashbots API has already caught up with (on shutdown)
+func (w *Watcher) flushBlockBacklog() {
+	if len(w.backlogHeights()) == 0 {
+		return
+	}
+
+	logging.Log.Infow("Flushing block backlog ...", "blocks", len(w.backlogHeights()))
+	w.processReadyBlocks()
+
+	for _, height := range w.backlogHeights() {
+		logging.Log.Warnw("Unprocessed block in backlog", "block", height)
+	}
+}
+
+// processBlockBacklog goes through the block-backlog (lowest block first), and processes those within the Flashbots API range
+func (w *Watcher) processBlockBacklog(flashbotsLatestBlockNumber int64) {
+	for {
+		blockFromBacklog := w.takeFromBacklog(flashbotsLatestBlockNumber)
+		if blockFromBacklog == nil {
+			return
+		}
+		if !w.processBlock(blockFromBacklog, w.SkipFlashbotsApi) {
+			return
+		}
+		time.Sleep(1 * time.Second)
+	}
+}
+
+// processBlock checks a block that was taken from the backlog, and handles its failed tx and errors. If the check
+// fails, the block is returned to the backlog and false is returned. With skipFlashbotsApi (and a Flashbots API for
+// the chain), the failed tx get FlashbotsUnknown.
+func (w *Watcher) processBlock(blockFromBacklog *blockswithtx.BlockWithTxReceipts, skipFlashbotsApi bool) bool {
+	height := blockFromBacklog.Block.Number().Int64()
+
+	if !noBlockInfo {
+		if logging.IsJson() || logging.ToFile() {
+			logging.Log.Infow("Processing block", "block", height, "hash", blockFromBacklog.Block.Hash().Hex(), "txs", len(blockFromBacklog.Block.Transactions()))
+		} else {
+			utils.PrintBlock(blockFromBacklog.Block)
+		}
+	}
+
+	timeStartCheck := time.Now()
+	check, err := blockcheck.CheckBlock(blockFromBacklog, skipFlashbotsApi)
+	metricBlockProcessingDuration.Observe(time.Since(timeStartCheck).Seconds())
+	if err != nil {
+		if errors.Is(err, api.ErrTimeout) {
+			logging.Log.Warnw("Flashbots API timeout, retrying block with the next header", "block", height, "error", err)
+		} else {
+			logging.Log.Errorw("CheckBlock from backlog error", "block", height, "error", err)
+		}
+		w.returnToBacklog(blockFromBacklog)
+		return false
+	}
+
+	// Without the Flashbots API data (-backlog-policy process), the Flashbots classification is unknown
+	if skipFlashbotsApi && !w.SkipFlashbotsApi && !check.ClassifiedByFeeRecipient {
+		for _, failedTx := range check.TxList() {
+			failedTx.FlashbotsUnknown = true
+		}
+	}
+
+	// no checking error, can process (after marking the failed tx of the blocks it replaces)
+	reorg, isReorg := w.checkReorg(height, blockFromBacklog.Block.Hash().Hex(), blockFromBacklog.Block.ParentHash().Hex())
+	if isReorg {
+		w.handleReorg(reorg)
+	}
+	handleFailedTxs(check, w.Handlers)
+	metricBlockHeight.Set(float64(height))
+	atomic.StoreInt64(&latestProcessedBlock, height)
+	if w.StateFile != "" {
+		err = saveState(w.StateFile, WatchState{LastProcessedBlock: height})
+		if err != nil {
+			logging.Log.Errorw("Error saving state file", "file", w.StateFile, "error", err)
+		}
+	}
+
+	// Handle errors in the bundle (print, Discord, etc.)
+	if check.HasErrors() {
+		if check.HasSeriousErrors() { // only serious errors are printed and sent to Discord
+			w.errorCountSerious += 1
+			if logging.IsJson() {
+				logging.Log.Warnw("Block has serious errors", "block", height, "errors", check.Errors)
+			} else {
+				msg := check.Sprint(true, false, true)
+				fmt.Fprintln(w.Out, msg)
+			}
+
+			// if sendErrorsToDiscord {
+			// 	if len(check.Errors) == 1 && check.HasBundleWith0EffectiveGasPrice {
+			// 		// Short message if only 1 error and that is a 0-effective-gas-price
+			// 		msg := check.SprintHeader(false, true)
+			// 		msg += " - Error: " + check.Errors[0]
+			// 		SendToDiscord(msg)
+			// 	} else {
+			// 		SendToDiscord(check.Sprint(false, true))
+			// 	}
+			// }
+			if !logging.IsJson() {
+				fmt.Fprintln(w.Out, "")
+			}
+		} else if check.HasLessSeriousErrors() { // less serious errors are only counted
+			w.errorCountNonSerious += 1
+		}
+
+		// Send failed TX to Discord
+		// if sendErrorsToDiscord && check.TriggerAlertOnFailedTx {
+		// 	SendToDiscord(check.Sprint(false, true, false))
+		// }
+
+		// Count errors
+		if check.HasSeriousErrors() || check.HasLessSeriousErrors() { // update and print miner error count on serious and less-serious errors
+			logging.Log.Infow("stats", "50p_errors", w.errorCountSerious, "25p_errors", w.errorCountNonSerious)
+			w.weeklyErrorSummary.AddCheckErrors(check)
+			w.dailyErrorSummary.AddCheckErrors(check)
+			if !logging.IsJson() {
+				fmt.Fprintln(w.Out, w.dailyErrorSummary.String())
+			}
+		}
+	}
+
+	// IS IT TIME TO RESET DAILY & WEEKLY ERRORS?
+	now := time.Now()
+
+	// Daily summary at 3pm ET
+	dailySummaryTriggerHourUtc := 19 // 3pm ET
+	// log.Println(now.UTC().Hour(), dailySummaryTriggerHourUtc, time.Since(w.dailyErrorSummary.TimeStarted).Hours())
+	if now.UTC().Hour() == dailySummaryTriggerHourUtc && time.Since(w.dailyErrorSummary.TimeStarted).Hours() >= 2 {
+		logging.Log.Info("trigger daily summary")
+		if sendErrorsToDiscord {
+			msg := w.dailyErrorSummary.String()
+			if msg != "" {
+				fmt.Fprintln(w.Out, msg)
+				SendToDiscord("Daily miner summary: ```" + msg + "```")
+			}
+		}
+
+		// reset daily summery
+		w.dailyErrorSummary.Reset()
+	}
+
+	// Weekly summary on Friday at 10am ET
+	weeklySummaryTriggerHourUtc := 14 // 10am ET
+	if now.UTC().Weekday() == time.Friday && now.UTC().Hour() == weeklySummaryTriggerHourUtc && time.Since(w.weeklyErrorSummary.TimeStarted).Hours() >= 2 {
+		logging.Log.Info("trigger weekly summary")
+		if sendErrorsToDiscord {
+			msg := w.weeklyErrorSummary.String()
+			if msg != "" {
+				fmt.Fprintln(w.Out, msg)
+				SendToDiscord("Weekly miner summary: ```" + msg + "```")
+			}
+		}
+
+		// reset weekly summery
+		w.weeklyErrorSummary.Reset()
+	}
+
+	// // -------- Send daily summary to Discord ---------
+	// if sendErrorsToDiscord {
+	// 	// Check if it's time to send to Discord: first block after 3pm ET (7pm UTC)
+	// 	// triggerHourUtc := 19
+
+	// 	// dateLastSent := lastSummarySentToDiscord.Format("01-02-2006")
+	// 	// dateToday := now.Format("01-02-2006")
+
+	// 	// For testing, send at specific interval
+	// 	if time.Since(w.dailyErrorSummary.TimeStarted).Hours() >= 3 {
+	// 		// if dateToday != dateLastSent && now.UTC().Hour() == triggerHourUtc {
+	// 		log.Println("Sending summary to Discord:")
+	// 		msg := w.dailyErrorSummary.String()
+	// 		if msg != "" {
+	// 			fmt.Println(msg)
+	// 			SendToDiscord("```" + msg + "```")
+	// 		}
+
+	// 		// Reset errors
+	// 		w.dailyErrorSummary.Reset()
+	// 		log.Println("Done, errors are reset.")
+	// 	}
+	// }
+
+	return true
+}
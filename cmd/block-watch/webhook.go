@@ -0,0 +1,79 @@
+// Generic webhook: POSTs every failed tx as JSON to a URL
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/metachris/flashbots/blockcheck"
+	"github.com/metachris/flashbots/logging"
+)
+
+const webhookQueueSize = 100
+
+// WebhookPayload is the JSON body of a webhook request
+type WebhookPayload struct {
+	blockcheck.FailedTx
+}
+
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// webhookSender queues the failed tx, the queue is drained by a single goroutine so slow webhooks never block
+// block processing
+type webhookSender struct {
+	url           string
+	flashbotsOnly bool
+	queue         chan WebhookPayload
+}
+
+// NewWebhookSender starts the goroutine that sends the queued failed tx to url
+func NewWebhookSender(url string, flashbotsOnly bool) *webhookSender {
+	sender := &webhookSender{url: url, flashbotsOnly: flashbotsOnly, queue: make(chan WebhookPayload, webhookQueueSize)}
+
+	go func() {
+		for payload := range sender.queue {
+			err := sendToWebhook(sender.url, payload)
+			if err != nil {
+				logging.Log.Errorw("Webhook error", "hash", payload.Hash, "error", err)
+			}
+		}
+	}()
+	return sender
+}
+
+// OnFailedTxs adds the failed tx to the webhook queue, or drops them if the queue is full
+func (s *webhookSender) OnFailedTxs(block *types.Block, failedTxs []blockcheck.FailedTx) {
+	for _, failedTx := range failedTxs {
+		if s.flashbotsOnly && !failedTx.IsFlashbots {
+			continue
+		}
+
+		select {
+		case s.queue <- WebhookPayload{FailedTx: failedTx}:
+		default:
+			logging.Log.Warnw("Webhook queue is full, dropping failed tx", "hash", failedTx.Hash, "block", failedTx.Block)
+		}
+	}
+}
+
+func sendToWebhook(url string, payload WebhookPayload) error {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	res, err := webhookClient.Post(url, "application/json", bytes.NewBuffer(payloadBytes))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("webhook response status: %s", res.Status)
+	}
+	return nil
+}
@@ -0,0 +1,325 @@
+// Webserver for watch mode
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/metachris/flashbots/blockcheck"
+	"github.com/metachris/flashbots/common"
+	"github.com/metachris/flashbots/logging"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var webserver *http.Server
+
+type readinessResponse struct {
+	Ready                bool  `json:"ready"`
+	ActiveNodes          int32 `json:"activeNodes"`
+	LatestProcessedBlock int64 `json:"latestProcessedBlock"`
+	FlashbotsLatestBlock int64 `json:"flashbotsLatestBlock"`
+	FlashbotsLagBlocks   int64 `json:"flashbotsLagBlocks"` // blocks the Flashbots API is behind the nodes
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+const failedTxDefaultLimit = 100
+
+// failedTxQuery holds optional filters, pagination and format for the /failedTx endpoint
+type failedTxQuery struct {
+	FromBlock     uint64
+	ToBlock       uint64
+	FlashbotsOnly bool
+	From          string // sender address (lowercase), only used with FilterFrom
+	FilterFrom    bool   // also set for an empty from, which matches no entries
+
+	Limit  int
+	Offset int
+	Order  string // asc or desc (default, newest first)
+	Format string // legacy: bare array without pagination
+}
+
+type failedTxResponse struct {
+	Total  int                   `json:"total"`
+	Limit  int                   `json:"limit"`
+	Offset int                   `json:"offset"`
+	Items  []blockcheck.FailedTx `json:"items"`
+}
+
+func parseFailedTxQuery(values url.Values) (query failedTxQuery, err error) {
+	if s := values.Get("fromBlock"); s != "" {
+		query.FromBlock, err = strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return query, fmt.Errorf("invalid fromBlock: %s", s)
+		}
+	}
+
+	if s := values.Get("toBlock"); s != "" {
+		query.ToBlock, err = strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return query, fmt.Errorf("invalid toBlock: %s", s)
+		}
+	}
+
+	if s := values.Get("flashbotsOnly"); s != "" {
+		query.FlashbotsOnly, err = strconv.ParseBool(s)
+		if err != nil {
+			return query, fmt.Errorf("invalid flashbotsOnly: %s", s)
+		}
+	}
+
+	if from, found := values["from"]; found {
+		query.From = strings.ToLower(from[0])
+		query.FilterFrom = true
+	}
+
+	query.Limit = failedTxDefaultLimit
+	if s := values.Get("limit"); s != "" {
+		query.Limit, err = strconv.Atoi(s)
+		if err != nil || query.Limit < 0 {
+			return query, fmt.Errorf("invalid limit: %s", s)
+		}
+	}
+
+	if s := values.Get("offset"); s != "" {
+		query.Offset, err = strconv.Atoi(s)
+		if err != nil || query.Offset < 0 {
+			return query, fmt.Errorf("invalid offset: %s", s)
+		}
+	}
+
+	query.Order = values.Get("order")
+	if query.Order == "" {
+		query.Order = "desc"
+	} else if query.Order != "asc" && query.Order != "desc" {
+		return query, fmt.Errorf("invalid order: %s", query.Order)
+	}
+
+	query.Format = values.Get("format")
+	if query.Format != "" && query.Format != "legacy" {
+		return query, fmt.Errorf("invalid format: %s", query.Format)
+	}
+
+	return query, nil
+}
+
+func (q failedTxQuery) Matches(failedTx blockcheck.FailedTx) bool {
+	if q.FromBlock > 0 && failedTx.Block < q.FromBlock {
+		return false
+	}
+	if q.ToBlock > 0 && failedTx.Block > q.ToBlock {
+		return false
+	}
+	if q.FlashbotsOnly && !failedTx.IsFlashbots {
+		return false
+	}
+	if q.FilterFrom && strings.ToLower(failedTx.From) != q.From {
+		return false
+	}
+	return true
+}
+
+// startWebserver starts serving on addr (in the background). /failedTx serves the entries of history, /stats the counts
+// of stats, /byContract the failed tx per recipient in history, /senders the failed tx per sender in history, /stuck
+// the stuck senders in history, /bundles the likely failed bundles in history, /reorgs the reorg events of reorgs,
+// /version the build info, /openapi.json the OpenAPI document of the endpoints (openApiEndpoints), /ws pushes the failed
+// tx of wsHub, /pending serves the pending candidates (only if pending is not nil). If authToken is not empty, all
+// endpoints except /health require it (see requireAuthToken).
+func startWebserver(addr string, authToken string, history *FailedTxHistory, stats *FailedTxStats, wsHub *WebsocketHub, pending *PendingTxHistory, reorgs *ReorgHistory) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", healthHandler)
+	mux.HandleFunc("/ready", readyHandler)
+	mux.HandleFunc("/failedTx", failedTxHistoryHandler(history))
+	mux.HandleFunc("/stats", func(w http.ResponseWriter, r *http.Request) {
+		respondJson(w, http.StatusOK, stats.Get())
+	})
+	mux.HandleFunc("/byContract", func(w http.ResponseWriter, r *http.Request) {
+		top := 0
+		if s := r.URL.Query().Get("top"); s != "" {
+			var err error
+			top, err = strconv.Atoi(s)
+			if err != nil || top < 0 {
+				respondJson(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("invalid top: %s", s)})
+				return
+			}
+		}
+		respondJson(w, http.StatusOK, countFailuresByContract(history.List(), top))
+	})
+	mux.HandleFunc("/senders", func(w http.ResponseWriter, r *http.Request) {
+		top := 0
+		if s := r.URL.Query().Get("top"); s != "" {
+			var err error
+			top, err = strconv.Atoi(s)
+			if err != nil || top < 0 {
+				respondJson(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("invalid top: %s", s)})
+				return
+			}
+		}
+		sortBy := r.URL.Query().Get("sortBy")
+		if sortBy == "" {
+			sortBy = sendersSortByCount
+		} else if sortBy != sendersSortByCount && sortBy != sendersSortByGas {
+			respondJson(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("invalid sortBy: %s (use %s or %s)", sortBy, sendersSortByCount, sendersSortByGas)})
+			return
+		}
+		respondJson(w, http.StatusOK, countFailuresBySender(history.List(), top, sortBy))
+	})
+	mux.HandleFunc("/stuck", func(w http.ResponseWriter, r *http.Request) {
+		respondJson(w, http.StatusOK, findStuckSenders(history.List()))
+	})
+	mux.HandleFunc("/bundles", func(w http.ResponseWriter, r *http.Request) {
+		respondJson(w, http.StatusOK, findFailedBundles(history.List()))
+	})
+	mux.HandleFunc("/reorgs", func(w http.ResponseWriter, r *http.Request) {
+		respondJson(w, http.StatusOK, reorgs.List())
+	})
+	mux.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) {
+		respondJson(w, http.StatusOK, common.GetBuildInfo())
+	})
+	openApiDocument := newOpenApiDocument(pending != nil, authToken != "")
+	mux.HandleFunc("/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		respondJson(w, http.StatusOK, openApiDocument)
+	})
+	mux.Handle("/ws", wsHub)
+	if pending != nil {
+		mux.HandleFunc("/pending", func(w http.ResponseWriter, r *http.Request) {
+			respondJson(w, http.StatusOK, pending.List())
+		})
+	}
+	mux.Handle("/metrics", promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer, promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{
+		EnableOpenMetrics: true, // exemplars are only included in the OpenMetrics format
+	})))
+	var handler http.Handler = mux
+	if authToken != "" {
+		handler = requireAuthToken(authToken, mux)
+	}
+	webserver = &http.Server{Addr: addr, Handler: handler}
+
+	logging.Log.Infow("Starting webserver", "addr", addr)
+	go func() {
+		err := webserver.ListenAndServe()
+		if err != nil && err != http.ErrServerClosed {
+			logging.Log.Fatalw("Webserver error", "error", err)
+		}
+	}()
+}
+
+// requireAuthToken only passes requests with the header "Authorization: Bearer <token>" to next, and responds with 401
+// to all others. /health is always passed, for liveness probes.
+func requireAuthToken(token string, next http.Handler) http.Handler {
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			respondJson(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// stopWebserver shuts down the webserver, giving open requests a few seconds to finish
+func stopWebserver() {
+	if webserver == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := webserver.Shutdown(ctx)
+	if err != nil {
+		logging.Log.Errorw("Webserver shutdown error", "error", err)
+	}
+}
+
+func respondJson(w http.ResponseWriter, status int, response interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(response)
+	if err != nil {
+		logging.Log.Errorw("Webserver response error", "error", err)
+	}
+}
+
+// failedTxHistoryHandler returns a handler for the recent failed transactions of history.
+//
+// Query args: fromBlock, toBlock, flashbotsOnly, from (sender address, case-insensitive) (filters), limit, offset,
+// order=asc|desc (pagination), format=legacy (bare array of all matching entries, oldest first)
+func failedTxHistoryHandler(history *FailedTxHistory) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		serveFailedTxHistory(w, r, history.List())
+	}
+}
+
+func serveFailedTxHistory(w http.ResponseWriter, r *http.Request, history []blockcheck.FailedTx) {
+	query, err := parseFailedTxQuery(r.URL.Query())
+	if err != nil {
+		respondJson(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+
+	items := make([]blockcheck.FailedTx, 0)
+	for _, failedTx := range history {
+		if query.Matches(failedTx) {
+			items = append(items, failedTx)
+		}
+	}
+
+	if query.Format == "legacy" {
+		respondJson(w, http.StatusOK, items)
+		return
+	}
+
+	if query.Order == "desc" {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	response := failedTxResponse{Total: len(items), Limit: query.Limit, Offset: query.Offset}
+	start := query.Offset
+	if start > len(items) {
+		start = len(items)
+	}
+	end := start + query.Limit
+	if query.Limit == 0 || end > len(items) {
+		end = len(items)
+	}
+	response.Items = items[start:end]
+
+	respondJson(w, http.StatusOK, response)
+}
+
+// healthHandler returns 200 as long as the webserver is running (liveness probe)
+func healthHandler(w http.ResponseWriter, r *http.Request) {
+	respondJson(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// readyHandler returns 200 once a block was processed and at least one node subscription is active, otherwise 503
+// (readiness probe)
+func readyHandler(w http.ResponseWriter, r *http.Request) {
+	response := readinessResponse{
+		ActiveNodes:          atomic.LoadInt32(&numActiveNodes),
+		LatestProcessedBlock: atomic.LoadInt64(&latestProcessedBlock),
+		FlashbotsLatestBlock: atomic.LoadInt64(&latestFlashbotsBlock),
+		FlashbotsLagBlocks:   flashbotsApiLag(),
+	}
+	response.Ready = response.LatestProcessedBlock > 0 && response.ActiveNodes > 0
+
+	status := http.StatusOK
+	if !response.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	respondJson(w, status, response)
+}
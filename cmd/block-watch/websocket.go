@@ -0,0 +1,133 @@
+// WebSocket push of failed tx to connected clients (/ws)
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/gorilla/websocket"
+	"github.com/metachris/flashbots/blockcheck"
+	"github.com/metachris/flashbots/logging"
+)
+
+// Messages per client that are buffered while it's reading slowly. If the buffer is full, the client is disconnected.
+const wsClientBufferSize = 256
+
+const wsWriteTimeout = 10 * time.Second
+
+var wsUpgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true }, // dashboards are usually served from another origin
+}
+
+type wsClient struct {
+	conn *websocket.Conn
+	send chan blockcheck.FailedTx
+}
+
+// WebsocketHub sends every failed tx to all connected clients (FailedTxHandler)
+type WebsocketHub struct {
+	lock    sync.Mutex
+	clients map[*wsClient]bool
+	history *FailedTxHistory // sent to new clients with ?backlog=true
+}
+
+func NewWebsocketHub(history *FailedTxHistory) *WebsocketHub {
+	return &WebsocketHub{clients: make(map[*wsClient]bool), history: history}
+}
+
+func (h *WebsocketHub) OnFailedTxs(block *types.Block, failedTxs []blockcheck.FailedTx) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	for client := range h.clients {
+	sendLoop:
+		for _, failedTx := range failedTxs {
+			select {
+			case client.send <- failedTx:
+			default:
+				logging.Log.Warnw("WebSocket client too slow, disconnecting", "remoteAddr", client.conn.RemoteAddr())
+				h.removeClient(client)
+				break sendLoop
+			}
+		}
+	}
+}
+
+// removeClient closes the send channel of the client, which makes its writer close the connection. Requires h.lock.
+func (h *WebsocketHub) removeClient(client *wsClient) {
+	if h.clients[client] {
+		delete(h.clients, client)
+		close(client.send)
+	}
+}
+
+// ServeHTTP upgrades the connection and registers the client. With ?backlog=true the client first receives the history.
+func (h *WebsocketHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		logging.Log.Debugw("WebSocket upgrade error", "error", err)
+		return
+	}
+
+	client := &wsClient{conn: conn, send: make(chan blockcheck.FailedTx, wsClientBufferSize)}
+	var backlog []blockcheck.FailedTx
+	if r.URL.Query().Get("backlog") == "true" {
+		backlog = h.history.List()
+	}
+
+	h.lock.Lock()
+	h.clients[client] = true
+	h.lock.Unlock()
+	logging.Log.Debugw("WebSocket client connected", "remoteAddr", conn.RemoteAddr())
+
+	go h.writeMessages(client, backlog)
+	h.readMessages(client)
+}
+
+// writeMessages sends the backlog, then the failed tx of the send channel until it is closed
+func (h *WebsocketHub) writeMessages(client *wsClient, backlog []blockcheck.FailedTx) {
+	defer client.conn.Close()
+
+	for _, failedTx := range backlog {
+		if !h.writeMessage(client, failedTx) {
+			return
+		}
+	}
+
+	for failedTx := range client.send {
+		if !h.writeMessage(client, failedTx) {
+			return
+		}
+	}
+	client.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(wsWriteTimeout))
+}
+
+func (h *WebsocketHub) writeMessage(client *wsClient, failedTx blockcheck.FailedTx) bool {
+	client.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	err := client.conn.WriteJSON(failedTx)
+	if err != nil {
+		logging.Log.Debugw("WebSocket write error", "remoteAddr", client.conn.RemoteAddr(), "error", err)
+		h.lock.Lock()
+		h.removeClient(client)
+		h.lock.Unlock()
+		return false
+	}
+	return true
+}
+
+// readMessages discards incoming messages (needed to process control messages) and removes the client on disconnect
+func (h *WebsocketHub) readMessages(client *wsClient) {
+	for {
+		if _, _, err := client.conn.ReadMessage(); err != nil {
+			break
+		}
+	}
+
+	logging.Log.Debugw("WebSocket client disconnected", "remoteAddr", client.conn.RemoteAddr())
+	h.lock.Lock()
+	h.removeClient(client)
+	h.lock.Unlock()
+	client.conn.Close()
+}
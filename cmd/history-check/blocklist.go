@@ -0,0 +1,71 @@
+// Reading a list of block numbers to check (-blocks-from) instead of a range, and sampling a range (-sample)
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// loadBlockList reads the block numbers of the file at path, or of stdin if path is "-"
+func loadBlockList(path string) ([]int64, error) {
+	if path == "-" {
+		return readBlockList(os.Stdin)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readBlockList(f)
+}
+
+// readBlockList reads one block number per line, in the given order. Empty lines and lines starting with # are
+// skipped, and every block is returned only once.
+func readBlockList(r io.Reader) (heights []int64, err error) {
+	seen := make(map[int64]bool)
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		height, err := strconv.ParseInt(line, 10, 64)
+		if err != nil || height <= 0 {
+			return nil, fmt.Errorf("line %d: invalid block number %q", lineNumber, line)
+		}
+		if !seen[height] {
+			seen[height] = true
+			heights = append(heights, height)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(heights) == 0 {
+		return nil, fmt.Errorf("no block numbers")
+	}
+	return heights, nil
+}
+
+// sampleBlocks returns every sample-th block of the range: startBlock, startBlock+sample, ... (up to endBlock)
+func sampleBlocks(startBlock int64, endBlock int64, sample int64) []int64 {
+	heights := make([]int64, 0, sampledBlockCount(startBlock, endBlock, sample))
+	for height := startBlock; height <= endBlock; height += sample {
+		heights = append(heights, height)
+	}
+	return heights
+}
+
+// sampledBlockCount returns the number of blocks of sampleBlocks, without building the list
+func sampledBlockCount(startBlock int64, endBlock int64, sample int64) int64 {
+	if endBlock < startBlock {
+		return 0
+	}
+	return (endBlock-startBlock)/sample + 1
+}
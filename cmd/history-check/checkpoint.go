@@ -0,0 +1,180 @@
+// Checkpoint file of a range scan (-checkpoint-file), to resume it after a crash
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/metachris/flashbots/blockcheck"
+)
+
+const checkpointSaveInterval = 5 * time.Second
+
+// Checkpoint is the range of a scan and the highest block up to which all blocks of the range were processed
+type Checkpoint struct {
+	StartBlock         int64 `json:"startBlock"`
+	EndBlock           int64 `json:"endBlock"`
+	Sample             int64 `json:"sample"`
+	LastCompletedBlock int64 `json:"lastCompletedBlock"` // startBlock-sample if no block was completed yet
+
+	// Sizes (in bytes) of the output files (-output-file, -csv) with all failed tx up to LastCompletedBlock, to which
+	// they are truncated when resuming
+	OutputSizes map[string]int64 `json:"outputSizes,omitempty"`
+}
+
+// loadCheckpoint reads the checkpoint file. Returns nil if it doesn't exist yet.
+func loadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var checkpoint Checkpoint
+	err = json.Unmarshal(data, &checkpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &checkpoint, nil
+}
+
+// saveCheckpoint writes the checkpoint to a temporary file and renames it, so the checkpoint file is never partially
+// written
+func saveCheckpoint(path string, checkpoint Checkpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	tmpPath := path + ".tmp"
+	err = ioutil.WriteFile(tmpPath, data, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// resumeBlock returns the first block to check: the block after LastCompletedBlock if the checkpoint is of the same
+// range, or an error if it's of another range. done is true if the whole range was already processed.
+func (c *Checkpoint) resumeBlock(startBlock int64, endBlock int64, sample int64) (block int64, done bool, err error) {
+	if c.StartBlock != startBlock || c.EndBlock != endBlock || c.Sample != sample {
+		return 0, false, fmt.Errorf("checkpoint file is of blocks %d to %d (sample %d), not %d to %d (sample %d): use the same range, or another checkpoint file", c.StartBlock, c.EndBlock, c.Sample, startBlock, endBlock, sample)
+	}
+	if c.LastCompletedBlock < startBlock-sample || (c.LastCompletedBlock-startBlock)%sample != 0 {
+		return 0, false, fmt.Errorf("checkpoint file has an invalid last completed block %d", c.LastCompletedBlock)
+	}
+
+	block = c.LastCompletedBlock + sample
+	return block, block > endBlock, nil
+}
+
+// truncateOutputs truncates the output files to their sizes at the last completed block, so the failed tx of the blocks
+// that were written after it (and are checked again) are not in the outputs twice. Files without a size in the
+// checkpoint (i.e. not an output of the checkpointed run) are not changed.
+func (c *Checkpoint) truncateOutputs(paths ...string) error {
+	for _, path := range paths {
+		size, found := c.OutputSizes[path]
+		if path == "" || !found {
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if info.Size() < size {
+			return fmt.Errorf("output file %s is smaller than at the checkpoint (%d < %d bytes)", path, info.Size(), size)
+		}
+		err = os.Truncate(path, size)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckpointTracker tracks the contiguous prefix of completed blocks of a range scan. The blocks are downloaded
+// concurrently and complete out of order, so a block only counts once all blocks before it are completed as well.
+// Their failed tx are only written to the outputs then, so the outputs are in block order and the output sizes in the
+// checkpoint include exactly the blocks up to LastCompletedBlock. Not safe for concurrent use (it's only called from
+// the block processor).
+type CheckpointTracker struct {
+	Path       string
+	Checkpoint Checkpoint
+	Write      func(records []blockcheck.FailedTx) // writes the failed tx of a block to the outputs
+	BeforeSave func(checkpoint *Checkpoint) error  // flushes the outputs and sets their sizes in the checkpoint
+
+	completed map[int64][]blockcheck.FailedTx // failed tx of the completed blocks after LastCompletedBlock (not written yet)
+	lastSaved int64
+	timeSaved time.Time
+}
+
+func NewCheckpointTracker(path string, checkpoint Checkpoint) *CheckpointTracker {
+	return &CheckpointTracker{Path: path, Checkpoint: checkpoint, completed: make(map[int64][]blockcheck.FailedTx), lastSaved: checkpoint.LastCompletedBlock, timeSaved: time.Now()}
+}
+
+// BlockDone marks the block as completed, writes the failed tx of the blocks that are now part of the prefix and saves
+// the checkpoint at most every checkpointSaveInterval
+func (t *CheckpointTracker) BlockDone(blockNumber int64, records []blockcheck.FailedTx) error {
+	if blockNumber <= t.Checkpoint.LastCompletedBlock {
+		return nil
+	}
+	t.completed[blockNumber] = records
+	for next := t.Checkpoint.LastCompletedBlock + t.Checkpoint.Sample; ; next += t.Checkpoint.Sample {
+		records, found := t.completed[next]
+		if !found {
+			break
+		}
+		delete(t.completed, next)
+		t.write(records)
+		t.Checkpoint.LastCompletedBlock = next
+	}
+
+	if time.Since(t.timeSaved) < checkpointSaveInterval || t.Checkpoint.LastCompletedBlock == t.lastSaved {
+		return nil
+	}
+	return t.Save()
+}
+
+// Save flushes the outputs and writes the checkpoint file
+func (t *CheckpointTracker) Save() error {
+	t.timeSaved = time.Now()
+	if t.BeforeSave != nil {
+		if err := t.BeforeSave(&t.Checkpoint); err != nil {
+			return err
+		}
+	}
+	if err := saveCheckpoint(t.Path, t.Checkpoint); err != nil {
+		return err
+	}
+	t.lastSaved = t.Checkpoint.LastCompletedBlock
+	return nil
+}
+
+// Finish saves the checkpoint, and then writes the failed tx of the blocks that completed after a gap (i.e. with a
+// timeout). They are not covered by the checkpoint, so they are truncated from the outputs when resuming.
+func (t *CheckpointTracker) Finish() error {
+	err := t.Save()
+
+	blocks := make([]int64, 0, len(t.completed))
+	for block := range t.completed {
+		blocks = append(blocks, block)
+	}
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i] < blocks[j] })
+	for _, block := range blocks {
+		t.write(t.completed[block])
+	}
+	t.completed = make(map[int64][]blockcheck.FailedTx)
+	return err
+}
+
+func (t *CheckpointTracker) write(records []blockcheck.FailedTx) {
+	if t.Write != nil && len(records) > 0 {
+		t.Write(records)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/metachris/flashbots/blockcheck"
+)
+
+func TestCheckpointTracker(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "scan.checkpoint")
+
+	written := make([]uint64, 0)
+	tracker := NewCheckpointTracker(path, Checkpoint{StartBlock: 100, EndBlock: 110, Sample: 2, LastCompletedBlock: 98})
+	tracker.Write = func(records []blockcheck.FailedTx) {
+		for _, record := range records {
+			written = append(written, record.Block)
+		}
+	}
+	tracker.BeforeSave = func(checkpoint *Checkpoint) error {
+		checkpoint.OutputSizes = map[string]int64{"failed.json": int64(len(written))}
+		return nil
+	}
+
+	// Blocks complete out of order, their failed tx are written once all blocks before them are completed
+	tracker.BlockDone(104, []blockcheck.FailedTx{{Block: 104}})
+	tracker.BlockDone(100, []blockcheck.FailedTx{{Block: 100}})
+	tracker.BlockDone(110, []blockcheck.FailedTx{{Block: 110}})
+	tracker.BlockDone(102, nil)
+	if tracker.Checkpoint.LastCompletedBlock != 104 {
+		t.Error("Wrong last completed block:", tracker.Checkpoint.LastCompletedBlock, "wanted:", 104)
+	}
+	if !reflect.DeepEqual(written, []uint64{100, 104}) {
+		t.Error("Wrong written blocks:", written, "wanted:", []uint64{100, 104})
+	}
+
+	// Finish saves the checkpoint before writing the blocks after the gap
+	if err := tracker.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(written, []uint64{100, 104, 110}) {
+		t.Error("Wrong written blocks:", written, "wanted:", []uint64{100, 104, 110})
+	}
+	checkpoint, err := loadCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checkpoint.LastCompletedBlock != 104 || checkpoint.OutputSizes["failed.json"] != 2 {
+		t.Error("Wrong checkpoint:", checkpoint)
+	}
+
+	block, done, err := checkpoint.resumeBlock(100, 110, 2)
+	if err != nil || done || block != 106 {
+		t.Error("Wrong resume block:", block, done, err, "wanted:", 106)
+	}
+	if _, _, err := checkpoint.resumeBlock(100, 120, 2); err == nil {
+		t.Error("Expected error for another range")
+	}
+}
+
+func TestCheckpointTruncateOutputs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "failed.json")
+	other := filepath.Join(dir, "other.json")
+	ioutil.WriteFile(path, []byte("line 1\nline 2\n"), 0644)
+	ioutil.WriteFile(other, []byte("line 1\nline 2\n"), 0644)
+
+	checkpoint := Checkpoint{OutputSizes: map[string]int64{path: 7}}
+	if err := checkpoint.truncateOutputs(path, other, ""); err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]string{path: "line 1\n", other: "line 1\nline 2\n"} {
+		content, _ := ioutil.ReadFile(path)
+		if string(content) != want {
+			t.Error("Wrong content of", path, ":", string(content), "wanted:", want)
+		}
+	}
+
+	checkpoint.OutputSizes[path] = 100
+	if err := checkpoint.truncateOutputs(path); err == nil {
+		t.Error("Expected error for a file smaller than at the checkpoint")
+	}
+}
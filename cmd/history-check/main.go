@@ -1,102 +1,708 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
-	"log"
+	"io"
 	"os"
+	"strings"
 	"sync"
 	"time"
+	_ "time/tzdata" // for -tz, also without zoneinfo on the system (i.e. in containers)
 
+	ethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/metachris/flashbots/api"
 	"github.com/metachris/flashbots/blockcheck"
+	"github.com/metachris/flashbots/common"
+	"github.com/metachris/flashbots/logging"
 	"github.com/metachris/go-ethutils/blockswithtx"
 	"github.com/metachris/go-ethutils/utils"
 )
 
 var errorSummary blockcheck.ErrorSummary = blockcheck.NewErrorSummary()
 
-func main() {
-	log.SetOutput(os.Stdout)
+var silent bool
+var summaryOnly bool // only count the failed tx (-summary-only)
+var ndjsonWriter *NdjsonWriter
+var csvWriter *CsvWriter
+var blockDirWriter *BlockDirWriter
+
+// infoOut receives all human-readable output (stderr if the NDJSON stream goes to stdout)
+var infoOut io.Writer = os.Stdout
 
+func main() {
 	ethUri := flag.String("eth", os.Getenv("ETH_NODE"), "Ethereum node URI")
-	startDate := flag.String("start", "", "date (yyyy-mm-dd)")
-	endDate := flag.String("end", "", "date (yyyy-mm-dd)")
+	requireSyncedPtr := flag.Bool("require-synced", false, "refuse to start if the eth node is still syncing (by default only a warning is logged)")
+	rpcHeaders := make(common.RpcHeaders)
+	flag.Var(rpcHeaders, "rpc-header", "HTTP header sent with every request to an HTTP eth node, i.e. \"Authorization: Bearer <token>\" (repeatable)")
+	startDate := flag.String("start", "", "date (yyyy-mm-dd or yyyy-mm-ddThh:mm, in -tz), RFC3339 timestamp (i.e. 2023-05-01T14:30:00Z), unix timestamp (i.e. @1620000000), or relative to now (i.e. -1d12h, units: w, d, h, m)")
+	endDate := flag.String("end", "", "date like -start (i.e. -1h), not before -start")
+	tzPtr := flag.String("tz", "UTC", "timezone of -start and -end dates without offset (i.e. America/New_York or Local)")
+	fromBlockPtr := flag.Int64("from-block", 0, "first block to check, instead of -start (requires -to-block)")
+	toBlockPtr := flag.Int64("to-block", 0, "last block to check (inclusive), instead of -end (requires -from-block)")
+	blocksFromPtr := flag.String("blocks-from", "", "check the block numbers in this file (one per line, - for stdin), instead of a range")
+	silentPtr := flag.Bool("silent", false, "don't print info about every block")
+	verbosePtr := flag.Bool("verbose", false, "debugging only, very chatty: log every tx of every checked block with its classification (zero gas price, data, receipt status, decision)")
+	outputPtr := flag.String("output", "", "output format for failed tx: ndjson")
+	outputFilePtr := flag.String("output-file", "", "write output to this file instead of stdout (gzip compressed if it ends in .gz)")
+	csvPtr := flag.String("csv", "", "append failed tx to this CSV file (gzip compressed if it ends in .gz)")
+	outputDirPtr := flag.String("output-dir", "", "write the failed tx of every block as JSON array to <dir>/<blockNumber>.json")
+	skipEmptyPtr := flag.Bool("skip-empty", false, "with -output-dir: don't write files for blocks without failed tx")
+	logFormatPtr := flag.String("log-format", logging.FormatText, "log format: text (with colored block output) or json")
+	logLevelPtr := flag.String("log-level", "info", "log level: debug, info, warn, error")
+	logFilePtr := flag.String("log-file", "", "write the logs and the human-readable output (also the summary) to this file instead of stdout, rotated by size")
+	logMaxSizePtr := flag.Int("log-max-size", 100, "with -log-file: rotate the log file when it reaches this size in MB")
+	logMaxBackupsPtr := flag.Int("log-max-backups", 5, "with -log-file: number of rotated log files to keep (<file>.1 is the newest, 0 keeps all)")
+	logMaxAgePtr := flag.Duration("log-max-age", 0, "with -log-file: remove rotated log files older than this (i.e. 168h, 0 keeps them)")
+	flashbotsApiPtr := flag.String("flashbots-api", "", "base URL of the Flashbots blocks API (default: the API of the chain)")
+	flashbotsTimeoutPtr := flag.Duration("flashbots-timeout", time.Minute, "timeout of Flashbots API requests (higher than in block-watch, the blocks are prefetched in requests of 10k blocks)")
+	strictFlashbotsPtr := flag.Bool("strict-flashbots", false, "treat Flashbots API responses with unexpected types or missing fields as errors, instead of decoding them as far as possible with a warning")
+	feeRecipientsPtr := flag.String("fee-recipients", "", "JSON file with fee recipients of Flashbots blocks and of blocks without Flashbots tx ({\"flashbots\": [...], \"other\": [...]}), to classify their tx without the Flashbots API (the other blocks are queried one by one, without prefetching)")
+	chainPtr := flag.String("chain", "", "chain name or id (mainnet, goerli, sepolia), instead of the chain id of the node")
+	explorerBasePtr := flag.String("explorer-base", "", "base URL of tx links, the tx hash is appended (default: the explorer of the chain, i.e. https://etherscan.io/tx/)")
+	minValuePtr := flag.String("min-value", "", "only record failed tx with at least this value (in ETH)")
+	minGasUsedPtr := flag.Uint64("min-gas-used", 0, "only record failed tx that used at least this much gas")
+	firstSeenPtr := flag.Bool("first-seen", false, "record every failed tx hash only once (i.e. if mined again after a reorg), duplicates are counted in the summary")
+	onlyFlashbotsPtr := flag.Bool("only-flashbots", false, "only write failed Flashbots tx (by the Flashbots API or the fee recipient with -fee-recipients), skip the other 0-gas tx")
+	includeSuccessPtr := flag.Bool("include-success", false, "also write successful Flashbots and other 0-gas tx to the outputs (with status \"success\")")
+	includeInternalPtr := flag.Bool("include-internal", false, "also write successful 0-gas tx with a reverted internal call to the outputs (status \"internal-revert\"), with debug_traceTransaction (one trace per successful 0-gas tx, needs the debug API)")
+	etherscanKeyPtr := flag.String("etherscan-key", os.Getenv("ETHERSCAN_API_KEY"), "Etherscan API key, to add the contract name and method to failed tx (one request per contract)")
+	repeatThresholdPtr := flag.Int("repeat-threshold", 3, "mark failed tx of senders with more failed tx than this in the current run (0 disables it)")
+	summaryOnlyPtr := flag.Bool("summary-only", false, "only count the failed tx and print the summary at the end (no per-block output, no failed tx outputs), for large ranges")
+	summaryJsonPtr := flag.Bool("summary-json", false, "print the run summary as JSON object to stdout at the end")
+	checkPtr := flag.Bool("check", false, "pre-flight check: query the chain id and latest block of the eth node, the latest block of the Flashbots API and fetch one block with receipts, print pass / fail for every step and exit (non-zero if a step failed)")
+	estimatePtr := flag.Bool("estimate", false, "only print the resolved block range and number of blocks, then exit")
+	concurrencyPtr := flag.Int("concurrency", 15, "number of concurrent block downloads from the eth node (higher is faster on a local node, lower avoids rate limits of remote nodes)")
+	progressPtr := flag.Int64("progress", 1000, "log the progress every n blocks, also with -silent (0 disables it)")
+	timeoutPtr := flag.Duration("timeout", 0, "stop fetching new blocks after this duration (i.e. 10m), and print the summary of the blocks checked until then (0 disables it)")
+	maxBlocksPtr := flag.Int64("max-blocks", 50_000, "refuse to start if more blocks than this would be checked, against accidental huge runs (0 disables it)")
+	checkpointFilePtr := flag.String("checkpoint-file", "", "JSON file with the last block up to which the range was checked (saved every few seconds), to resume the same range after a crash or -timeout (truncates -output-file and -csv to the checkpoint and appends to them)")
+	samplePtr := flag.Int64("sample", 1, "only fetch and check every nth block of the range (the first block, first+n, ...), the summary estimates the totals of the range")
 	flag.Parse()
 
-	if *startDate == "" || *endDate == "" {
-		log.Fatal("Missing date")
+	silent = *silentPtr
+	summaryOnly = *summaryOnlyPtr
+
+	if summaryOnly {
+		if *outputPtr != "" || *csvPtr != "" || *outputDirPtr != "" {
+			logging.Exitw(logging.ExitCodeInvalidArgs, "Invalid arguments", "error", "summary-only cannot be used with output, csv or output-dir")
+		}
+
+		// Only the summary is printed, unless -silent=false was passed explicitly
+		if !isFlagPassed("silent") {
+			silent = true
+		}
+	}
+
+	// Loaded before the outputs are created, to append to the NDJSON output file when resuming
+	var checkpoint *Checkpoint
+	if *checkpointFilePtr != "" {
+		if *blocksFromPtr != "" {
+			logging.Exitw(logging.ExitCodeInvalidArgs, "Invalid arguments", "error", "checkpoint-file cannot be used with blocks-from")
+		}
+		if strings.HasSuffix(*outputFilePtr, ".gz") || strings.HasSuffix(*csvPtr, ".gz") {
+			logging.Exitw(logging.ExitCodeInvalidArgs, "Invalid arguments", "error", "checkpoint-file cannot be used with compressed outputs (.gz), they are incomplete after a crash")
+		}
+
+		var err error
+		checkpoint, err = loadCheckpoint(*checkpointFilePtr)
+		if err != nil {
+			logging.Exitw(logging.ExitCodeInvalidArgs, "Error loading checkpoint file", "file", *checkpointFilePtr, "error", err)
+		}
+	}
+
+	if *outputPtr != "" && *outputPtr != OutputFormatNdjson {
+		logging.Exitw(logging.ExitCodeInvalidArgs, "Invalid output format", "output", *outputPtr)
+	}
+
+	if *outputPtr == OutputFormatNdjson {
+		if *outputFilePtr == "" {
+			infoOut = os.Stderr
+		}
+
+		// NDJSON output replaces the per-block output, unless -silent=false was passed explicitly
+		if !isFlagPassed("silent") {
+			silent = true
+		}
+
+		var err error
+		ndjsonWriter, err = NewNdjsonWriter(*outputFilePtr, checkpoint != nil)
+		if err != nil {
+			logging.Exitw(logging.ExitCodeError, "Error creating output file", "file", *outputFilePtr, "error", err)
+		}
+	}
+
+	// The NDJSON stream (without -output-file) and -summary-json stay on stdout
+	if *logFilePtr != "" {
+		logFile, err := logging.NewRotatingFile(*logFilePtr, *logMaxSizePtr, *logMaxBackupsPtr, *logMaxAgePtr)
+		if err != nil {
+			logging.Exitw(logging.ExitCodeInvalidArgs, "Error opening log file", "file", *logFilePtr, "error", err)
+		}
+		defer logFile.Close()
+		infoOut = logFile
+	}
+
+	err := logging.Setup(*logFormatPtr, *logLevelPtr, infoOut)
+	if err != nil {
+		logging.Exitw(logging.ExitCodeInvalidArgs, "Invalid arguments", "error", err)
+	}
+	logging.ToggleDebugOnSignal()
+
+	err = api.SetTimeout(*flashbotsTimeoutPtr)
+	if err != nil {
+		logging.Exitw(logging.ExitCodeInvalidArgs, "Invalid arguments", "error", err)
+	}
+	api.StrictResponses = *strictFlashbotsPtr
+
+	if *checkPtr { // doesn't need a block range
+		exitCode := runPreflightCheck(infoOut, *ethUri, rpcHeaders, *chainPtr, *flashbotsApiPtr)
+		if exitCode != 0 {
+			logging.Exitw(exitCode, "Pre-flight check failed")
+		}
+		return
+	}
+
+	blockcheck.EtherscanApiKey = *etherscanKeyPtr
+	blockcheck.FailedTxMinGasUsed = *minGasUsedPtr
+	blockcheck.IncludeSuccessfulTx = *includeSuccessPtr
+	blockcheck.OnlyFlashbotsTx = *onlyFlashbotsPtr
+	blockcheck.LogTxDecisions = *verbosePtr
+	blockcheck.DeduplicateFailedTx = *firstSeenPtr
+
+	if *repeatThresholdPtr < 0 {
+		logging.Exitw(logging.ExitCodeInvalidArgs, "Invalid arguments", "error", fmt.Sprintf("repeat-threshold: cannot be negative (%d)", *repeatThresholdPtr))
+	}
+	blockcheck.RepeatedSenderThreshold = *repeatThresholdPtr
+
+	if summaryOnly { // these only add fields to the failed tx records, which aren't written
+		blockcheck.EtherscanApiKey = ""
+		blockcheck.RepeatedSenderThreshold = 0
+	}
+
+	if *feeRecipientsPtr != "" {
+		err = blockcheck.LoadFlashbotsFeeRecipients(*feeRecipientsPtr)
+		if err != nil {
+			logging.Exitw(logging.ExitCodeInvalidArgs, "Invalid arguments", "error", fmt.Sprintf("fee-recipients: %v", err))
+		}
+	}
+
+	if *minValuePtr != "" {
+		blockcheck.FailedTxMinValue, err = common.EthStringToWei(*minValuePtr)
+		if err != nil {
+			logging.Exitw(logging.ExitCodeInvalidArgs, "Invalid arguments", "error", err)
+		}
+	}
+
+	if *concurrencyPtr < 1 {
+		logging.Exitw(logging.ExitCodeInvalidArgs, "Invalid arguments", "error", fmt.Sprintf("concurrency: must be at least 1 (%d)", *concurrencyPtr))
+	}
+
+	if *progressPtr < 0 {
+		logging.Exitw(logging.ExitCodeInvalidArgs, "Invalid arguments", "error", fmt.Sprintf("progress: cannot be negative (%d)", *progressPtr))
+	}
+
+	if *timeoutPtr < 0 {
+		logging.Exitw(logging.ExitCodeInvalidArgs, "Invalid arguments", "error", fmt.Sprintf("timeout: cannot be negative (%s)", *timeoutPtr))
+	}
+
+	if *maxBlocksPtr < 0 {
+		logging.Exitw(logging.ExitCodeInvalidArgs, "Invalid arguments", "error", fmt.Sprintf("max-blocks: cannot be negative (%d)", *maxBlocksPtr))
+	}
+
+	if *samplePtr < 1 {
+		logging.Exitw(logging.ExitCodeInvalidArgs, "Invalid arguments", "error", fmt.Sprintf("sample: must be at least 1 (%d)", *samplePtr))
+	}
+	if *samplePtr > 1 && *blocksFromPtr != "" {
+		logging.Exitw(logging.ExitCodeInvalidArgs, "Invalid arguments", "error", "sample cannot be used with blocks-from")
+	}
+
+	loc, err := time.LoadLocation(*tzPtr)
+	if err != nil {
+		logging.Exitw(logging.ExitCodeInvalidArgs, "Invalid arguments", "error", fmt.Sprintf("tz: %v", err))
+	}
+
+	err = validateRangeArgs(*fromBlockPtr, *toBlockPtr, *startDate, *endDate, *blocksFromPtr)
+	if err != nil {
+		logging.Exitw(logging.ExitCodeInvalidArgs, "Invalid arguments", "error", err)
+	}
+
+	var blockList []int64
+	if *blocksFromPtr != "" {
+		blockList, err = loadBlockList(*blocksFromPtr)
+		if err != nil {
+			logging.Exitw(logging.ExitCodeInvalidArgs, "Invalid arguments", "error", fmt.Sprintf("blocks-from: %v", err))
+		}
 	}
 
 	if *ethUri == "" {
-		log.Fatal("Missing eth node uri")
+		logging.Exitw(logging.ExitCodeInvalidArgs, "Missing eth node uri")
 	}
 
-	fmt.Printf("Connecting to %s ... ", *ethUri)
-	client, err := ethclient.Dial(*ethUri)
-	utils.Perror(err)
-	fmt.Printf("ok\n")
+	logging.Log.Infow("Connecting to eth node", "node", *ethUri)
+	client, err := common.DialEthClient(*ethUri, rpcHeaders)
+	if err != nil {
+		logging.Exitw(logging.ExitCodeEthNode, "Error connecting to eth node", "node", *ethUri, "error", err)
+	}
 
-	startTime, err := utils.DateToTime(*startDate, 0, 0, 0)
-	utils.Perror(err)
-	startBlockHeader, err := utils.GetFirstBlockHeaderAtOrAfterTime(client, startTime)
-	utils.Perror(err)
-	startBlock := startBlockHeader.Number.Int64()
+	err = common.CheckSyncProgress(client, *ethUri, *requireSyncedPtr)
+	if err != nil {
+		logging.Exitw(logging.ExitCodeEthNode, "Eth node is not synced", "node", *ethUri, "error", err)
+	}
+	common.ProbeBlockReceipts(client, *ethUri)
 
-	endTime, err := utils.DateToTime(*endDate, 0, 0, 0)
-	// endTime, err := utils.DateToTime(*startDate, 5, 10, 0)
-	utils.Perror(err)
-	endBlockHeader, err := utils.GetFirstBlockHeaderAtOrAfterTime(client, endTime)
-	utils.Perror(err)
-	endBlock := endBlockHeader.Number.Int64()
+	if *includeInternalPtr {
+		blockcheck.InternalCallTracer = func(hash ethcommon.Hash) (*common.CallFrame, error) {
+			return common.TraceTransaction(client, hash)
+		}
+	}
+
+	flashbotsApiAvailable, err := common.SetupChain(client, *chainPtr, *flashbotsApiPtr)
+	if err != nil {
+		logging.Exitw(exitCodeOf(err), "Error setting up chain", "error", err)
+	}
+	if *onlyFlashbotsPtr && !flashbotsApiAvailable && *feeRecipientsPtr == "" {
+		logging.Log.Warnw("No Flashbots API for this chain and no -fee-recipients, -only-flashbots records no tx", "chainId", common.ChainId)
+	}
+
+	if *explorerBasePtr != "" {
+		err = common.SetExplorerTxBaseUrl(*explorerBasePtr)
+		if err != nil {
+			logging.Exitw(logging.ExitCodeInvalidArgs, "Invalid arguments", "error", fmt.Sprintf("explorer-base: %v", err))
+		}
+	}
 
-	fmt.Println("blocks", startBlock, "...", endBlock)
+	maxBlocks := *maxBlocksPtr
+	if *estimatePtr { // always print the estimate
+		maxBlocks = 0
+	}
+
+	var startBlock, endBlock int64
+	if blockList != nil { // list of blocks, the range is only for the Startup log
+		startBlock, endBlock = blockListRange(blockList)
+		if maxBlocks > 0 && int64(len(blockList)) > maxBlocks {
+			err = fmt.Errorf("blocks-from has %d blocks, more than -max-blocks %d (use a higher -max-blocks, or 0 to disable the check)", len(blockList), maxBlocks)
+		}
+	} else if *fromBlockPtr != 0 { // explicit block range, without resolving dates
+		startBlock, endBlock = *fromBlockPtr, *toBlockPtr
+		err = checkMaxBlocks(startBlock, endBlock, *samplePtr, maxBlocks)
+	} else {
+		startBlock, endBlock, err = getBlockRangeFromArguments(client, *startDate, *endDate, loc, *samplePtr, maxBlocks)
+	}
+	if err != nil {
+		logging.Exitw(exitCodeOf(err), "Invalid block range", "error", err)
+	}
+
+	// With a checkpoint of the same range, the blocks up to its last completed block are skipped
+	firstBlock := startBlock
+	if checkpoint != nil {
+		var done bool
+		firstBlock, done, err = checkpoint.resumeBlock(startBlock, endBlock, *samplePtr)
+		if err != nil {
+			logging.Exitw(logging.ExitCodeInvalidArgs, "Invalid arguments", "error", err)
+		}
+		if done {
+			logging.Log.Infow("Range already completed according to the checkpoint file", "file", *checkpointFilePtr, "startBlock", startBlock, "endBlock", endBlock)
+			if ndjsonWriter != nil {
+				ndjsonWriter.Close()
+			}
+			return
+		}
+
+		// The failed tx of the blocks after the last completed block are written again
+		err = checkpoint.truncateOutputs(*outputFilePtr, *csvPtr)
+		if err != nil {
+			logging.Exitw(logging.ExitCodeError, "Error truncating output files to the checkpoint", "file", *checkpointFilePtr, "error", err)
+		}
+	} else if *checkpointFilePtr != "" {
+		checkpoint = &Checkpoint{StartBlock: startBlock, EndBlock: endBlock, Sample: *samplePtr, LastCompletedBlock: startBlock - *samplePtr}
+	}
+
+	numBlocks := sampledBlockCount(firstBlock, endBlock, *samplePtr)
+	if blockList != nil {
+		numBlocks = int64(len(blockList))
+	}
+
+	if *estimatePtr {
+		fmt.Printf("start block: %d\nend block:   %d\nblocks:      %d\n", startBlock, endBlock, numBlocks)
+		if firstBlock != startBlock {
+			fmt.Printf("resume from: %d\n", firstBlock)
+		}
+		if ndjsonWriter != nil {
+			ndjsonWriter.Close()
+		}
+		return
+	}
+
+	// One line with the resolved configuration, to see what an instance is doing from its logs
+	flashbotsApi := ""
+	if flashbotsApiAvailable {
+		flashbotsApi = api.BaseUrl
+	}
+	mode := "historical"
+	if summaryOnly {
+		mode = "summary-only"
+	}
+	buildInfo := common.GetBuildInfo()
+	logging.Log.Infow("Startup", "version", buildInfo.Version, "gitCommit", buildInfo.GitCommit, "buildDate", buildInfo.BuildDate, "goVersion", buildInfo.GoVersion,
+		"nodes", []string{common.RedactUri(*ethUri)}, "mode", mode,
+		"startBlock", startBlock, "endBlock", endBlock, "blocks", numBlocks, "sample", *samplePtr, "chainId", common.ChainId, "flashbotsApi", flashbotsApi)
+
+	logging.Log.Infow("Checking block range", "startBlock", startBlock, "endBlock", endBlock)
+	if firstBlock != startBlock {
+		logging.Log.Infow("Resuming from checkpoint", "file", *checkpointFilePtr, "lastCompletedBlock", checkpoint.LastCompletedBlock, "firstBlock", firstBlock, "blocks", numBlocks)
+	}
 
 	timestampMainStart := time.Now() // for measuring execution time
 
-	// Prefetch Flashbots blocks
-	fmt.Print("Caching flashbots blocks... ")
-	blockcheck.CacheFlashbotsBlocks(startBlock, endBlock)
-	fmt.Print("done\n")
+	// The timeout includes prefetching the Flashbots blocks, but only stops fetching blocks from the eth node
+	ctx := context.Background()
+	if *timeoutPtr > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeoutPtr)
+		defer cancel()
+	}
+
+	// Prefetch Flashbots blocks (the blocks of a list are queried one by one, they may be far apart). With
+	// -fee-recipients, only the blocks of unknown fee recipients are queried.
+	skipFlashbotsApi := true
+	if flashbotsApiAvailable && (blockList != nil || *feeRecipientsPtr != "") {
+		skipFlashbotsApi = false
+	} else if flashbotsApiAvailable {
+		logging.Log.Info("Caching flashbots blocks ...")
+		err = blockcheck.CacheFlashbotsBlocks(firstBlock, endBlock)
+		if errors.Is(err, blockcheck.ErrFlashbotsApiDoesntHaveThatBlockYet) {
+			logging.Log.Warnw("Flashbots API doesn't have the latest blocks of the range yet, they are not classified as Flashbots tx", "endBlock", endBlock)
+		} else if err != nil {
+			logging.Exitw(logging.ExitCodeFlashbotsApi, "Error caching Flashbots blocks", "error", err)
+		}
+	}
+
+	if *csvPtr != "" {
+		csvWriter, err = NewCsvWriter(*csvPtr)
+		if err != nil {
+			logging.Exitw(logging.ExitCodeError, "Error creating CSV file", "file", *csvPtr, "error", err)
+		}
+	}
+
+	if *outputDirPtr != "" {
+		blockDirWriter, err = NewBlockDirWriter(*outputDirPtr, *skipEmptyPtr)
+		if err != nil {
+			logging.Exitw(logging.ExitCodeError, "Error creating output directory", "dir", *outputDirPtr, "error", err)
+		}
+	}
 
 	// Start fetching blocks
 	blockChan := make(chan *blockswithtx.BlockWithTxReceipts, 100) // channel for resulting BlockWithTxReceipt
 
 	// Start block processor
-	var numBlocksProcessed int
-	var numTxProcessed int
+	runSummary := NewRunSummary()
+	runSummary.Sample = *samplePtr
+	if blockList == nil {
+		runSummary.rangeBlocks = endBlock - firstBlock + 1 // the blocks of this run, when resuming from a checkpoint
+	}
+	progress := NewProgress(*progressPtr, numBlocks)
+	var checkpointTracker *CheckpointTracker
+	if checkpoint != nil {
+		checkpointTracker = NewCheckpointTracker(*checkpointFilePtr, *checkpoint)
+		checkpointTracker.Write = writeOutputs
+		checkpointTracker.BeforeSave = func(checkpoint *Checkpoint) error {
+			return flushOutputs(checkpoint, *outputFilePtr, *csvPtr)
+		}
+
+		// Saved right away with the initial output sizes, so a crash before the next save doesn't append the failed tx
+		// of the same blocks again (-csv appends)
+		err = checkpointTracker.Save()
+		if err != nil {
+			logging.Exitw(logging.ExitCodeError, "Error saving checkpoint file", "file", *checkpointFilePtr, "error", err)
+		}
+	}
 	var analyzeLock sync.Mutex
 	go func() {
 		analyzeLock.Lock()
 		defer analyzeLock.Unlock() // we unlock when done
 
 		for block := range blockChan {
-			numBlocksProcessed += 1
-			numTxProcessed += len(block.Block.Transactions())
-			processBlockWithReceipts(block, client)
+			check, records := processBlockWithReceipts(block, client, skipFlashbotsApi)
+			runSummary.AddBlockCheck(check)
+			progress.BlockDone(block.Block.Number().Int64())
+			if checkpointTracker != nil { // writes the records in block order
+				err := checkpointTracker.BlockDone(block.Block.Number().Int64(), records)
+				if err != nil {
+					logging.Log.Errorw("Error saving checkpoint file", "file", *checkpointFilePtr, "error", err)
+				}
+			} else {
+				writeOutputs(records)
+			}
 		}
 	}()
 
 	// Start fetching and processing blocks
-	blockswithtx.GetBlocksWithTxReceipts(client, blockChan, startBlock, endBlock, 15)
+	if blockList != nil {
+		common.GetBlockListWithTxReceiptsContext(ctx, client, blockChan, blockList, *concurrencyPtr)
+	} else if *samplePtr > 1 {
+		common.GetBlockListWithTxReceiptsContext(ctx, client, blockChan, sampleBlocks(firstBlock, endBlock, *samplePtr), *concurrencyPtr)
+	} else {
+		common.GetBlocksWithTxReceiptsContext(ctx, client, blockChan, firstBlock, endBlock, *concurrencyPtr)
+	}
 
 	// Wait for processing to finish
-	fmt.Println("Waiting for Analysis workers...")
+	logging.Log.Info("Waiting for Analysis workers...")
 	close(blockChan)
 	analyzeLock.Lock() // wait until all blocks have been processed
 
-	fmt.Println(errorSummary.String())
+	if checkpointTracker != nil {
+		err = checkpointTracker.Finish()
+		if err != nil {
+			logging.Log.Errorw("Error saving checkpoint file", "file", *checkpointFilePtr, "error", err)
+		}
+	}
+
+	if ctx.Err() != nil {
+		runSummary.TimedOut = true
+		logging.Log.Warnw("Timeout reached, the summary covers only the blocks checked until then", "timeout", *timeoutPtr, "checkedBlocks", runSummary.Blocks, "blocks", numBlocks)
+	}
+
+	if ndjsonWriter != nil {
+		err = ndjsonWriter.Close()
+		if err != nil {
+			logging.Log.Errorw("Error writing output file", "file", *outputFilePtr, "error", err)
+		}
+	}
+
+	if csvWriter != nil {
+		err = csvWriter.Close()
+		if err != nil {
+			logging.Log.Errorw("Error writing CSV file", "file", *csvPtr, "error", err)
+		}
+	}
 
 	timeNeeded := time.Since(timestampMainStart)
-	fmt.Printf("Analysis of %s blocks, %s transactions finished in %.2fs\n", utils.NumberToHumanReadableString(numBlocksProcessed, 0), utils.NumberToHumanReadableString(numTxProcessed, 0), timeNeeded.Seconds())
+	if logging.IsJson() {
+		logging.Log.Infow("Analysis finished", "blocks", runSummary.Blocks, "txs", runSummary.Transactions, "duration", timeNeeded,
+			"failedTx", runSummary.FailedTx, "failedFlashbotsTx", runSummary.FailedFlashbotsTx, "failedOther0GasTx", runSummary.FailedOther0GasTx,
+			"uniqueSenders", runSummary.UniqueSenders, "mostFailuresBlock", runSummary.MostFailuresBlock, "mostFailuresCount", runSummary.MostFailuresCount,
+			"duplicateFailedTx", runSummary.DuplicateFailedTx, "internalRevertTx", runSummary.InternalRevertTx, "timedOut", runSummary.TimedOut,
+			"sample", runSummary.Sample, "estimatedFailedTx", runSummary.EstimatedFailedTx)
+	} else {
+		fmt.Fprintln(infoOut, errorSummary.String())
+		fmt.Fprintf(infoOut, "Analysis of %s blocks, %s transactions finished in %.2fs\n\n", utils.NumberToHumanReadableString(runSummary.Blocks, 0), utils.NumberToHumanReadableString(runSummary.Transactions, 0), timeNeeded.Seconds())
+		runSummary.Print(infoOut)
+	}
+
+	if *summaryJsonPtr {
+		err = runSummary.PrintJson(os.Stdout)
+		if err != nil {
+			logging.Log.Errorw("Error writing summary", "error", err)
+		}
+	}
+}
+
+// exitCodeOf returns the exit code for errors of eth node requests (wrapping common.ErrEthNode), or else for invalid
+// arguments
+func exitCodeOf(err error) int {
+	if errors.Is(err, common.ErrEthNode) {
+		return logging.ExitCodeEthNode
+	}
+	return logging.ExitCodeInvalidArgs
+}
+
+// getBlockRangeFromArguments resolves the start and end dates (without offset in loc) to the first blocks at or after
+// these dates. It fails if more than maxBlocks blocks of the range would be checked (0 disables the check).
+func getBlockRangeFromArguments(client *ethclient.Client, startDate string, endDate string, loc *time.Location, sample int64, maxBlocks int64) (startBlock int64, endBlock int64, err error) {
+	startTime, err := common.ParseDateArg(startDate, loc)
+	if err != nil {
+		return 0, 0, fmt.Errorf("start: %w", err)
+	}
+
+	endTime, err := common.ParseDateArg(endDate, loc)
+	if err != nil {
+		return 0, 0, fmt.Errorf("end: %w", err)
+	}
+
+	if endTime.Before(startTime) {
+		return 0, 0, fmt.Errorf("end (%s) is before start (%s)", endDate, startDate)
+	}
+
+	logging.Log.Infow("Resolving dates to blocks", "start", startTime.Format(time.RFC3339), "end", endTime.Format(time.RFC3339), "tz", loc.String())
+	startBlockHeader, err := utils.GetFirstBlockHeaderAtOrAfterTime(client, startTime)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w: %v", common.ErrEthNode, err)
+	}
+
+	endBlockHeader, err := utils.GetFirstBlockHeaderAtOrAfterTime(client, endTime)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w: %v", common.ErrEthNode, err)
+	}
+
+	startBlock, endBlock = startBlockHeader.Number.Int64(), endBlockHeader.Number.Int64()
+	if err = checkMaxBlocks(startBlock, endBlock, sample, maxBlocks); err != nil {
+		return 0, 0, err
+	}
+	return startBlock, endBlock, nil
+}
+
+// blockListRange returns the lowest and highest block of the list
+func blockListRange(heights []int64) (lowest int64, highest int64) {
+	lowest, highest = heights[0], heights[0]
+	for _, height := range heights {
+		if height < lowest {
+			lowest = height
+		}
+		if height > highest {
+			highest = height
+		}
+	}
+	return lowest, highest
+}
+
+// checkMaxBlocks fails if more than maxBlocks blocks of the range would be checked, with every sample-th block (0
+// disables the check)
+func checkMaxBlocks(startBlock int64, endBlock int64, sample int64, maxBlocks int64) error {
+	numBlocks := sampledBlockCount(startBlock, endBlock, sample)
+	if maxBlocks > 0 && numBlocks > maxBlocks && sample > 1 {
+		return fmt.Errorf("range %d - %d has %d blocks with -sample %d, more than -max-blocks %d (use a higher -sample or -max-blocks, or 0 to disable the check)", startBlock, endBlock, numBlocks, sample, maxBlocks)
+	} else if maxBlocks > 0 && numBlocks > maxBlocks {
+		return fmt.Errorf("range %d - %d has %d blocks, more than -max-blocks %d (use a higher -max-blocks, or 0 to disable the check)", startBlock, endBlock, numBlocks, maxBlocks)
+	}
+	return nil
+}
+
+// validateRangeArgs returns an error naming the invalid argument. The range is either given by -start and -end, or by
+// -from-block and -to-block. Instead of a range, -blocks-from gives a list of blocks.
+func validateRangeArgs(fromBlock int64, toBlock int64, startDate string, endDate string, blocksFrom string) error {
+	if blocksFrom != "" {
+		if fromBlock != 0 || toBlock != 0 || startDate != "" || endDate != "" {
+			return errors.New("blocks-from cannot be used together with from-block, to-block, start and end")
+		}
+		return nil
+	}
+
+	if fromBlock < 0 || toBlock < 0 {
+		return fmt.Errorf("from-block, to-block: cannot be negative (%d, %d)", fromBlock, toBlock)
+	}
+
+	if fromBlock != 0 || toBlock != 0 {
+		if startDate != "" || endDate != "" {
+			return errors.New("from-block and to-block cannot be used together with start and end")
+		}
+		if fromBlock == 0 || toBlock == 0 {
+			return errors.New("from-block and to-block must be used together")
+		}
+		if toBlock < fromBlock {
+			return fmt.Errorf("to-block: cannot be before from-block (%d < %d)", toBlock, fromBlock)
+		}
+		return nil
+	}
+
+	if startDate == "" || endDate == "" {
+		return errors.New("missing date: use -start and -end, -from-block and -to-block, or -blocks-from")
+	}
+	return nil
 }
 
-func processBlockWithReceipts(block *blockswithtx.BlockWithTxReceipts, client *ethclient.Client) {
-	utils.PrintBlock(block.Block)
-	check, err := blockcheck.CheckBlock(block, true)
-	utils.Perror(err)
+// processBlockWithReceipts checks the block and returns its failed (and with -include-success the successful) tx for
+// the NDJSON and CSV outputs (none with -summary-only). It writes the block file of -output-dir. Without
+// skipFlashbotsApi, blocks that are not prefetched are queried from the Flashbots API.
+func processBlockWithReceipts(block *blockswithtx.BlockWithTxReceipts, client *ethclient.Client, skipFlashbotsApi bool) (*blockcheck.BlockCheck, []blockcheck.FailedTx) {
+	if !silent {
+		if logging.IsJson() || logging.ToFile() {
+			logging.Log.Infow("Processing block", "block", block.Block.NumberU64(), "hash", block.Block.Hash().Hex(), "txs", len(block.Block.Transactions()))
+		} else {
+			utils.PrintBlock(block.Block)
+		}
+	}
+
+	check, err := blockcheck.CheckBlock(block, skipFlashbotsApi)
+	if errors.Is(err, blockcheck.ErrFlashbotsApiDoesntHaveThatBlockYet) {
+		logging.Log.Warnw("Flashbots API doesn't have the block yet, its tx are not classified as Flashbots tx", "block", block.Block.NumberU64())
+		check, err = blockcheck.CheckBlock(block, true)
+	}
+	if err != nil {
+		logging.Exitw(logging.ExitCodeFlashbotsApi, "CheckBlock error", "block", block.Block.NumberU64(), "error", err)
+	}
 
 	if check.HasSeriousErrors() || check.HasLessSeriousErrors() { // update and print miner error count on serious and less-serious errors
 		errorSummary.AddCheckErrors(check)
 	}
+
+	if summaryOnly { // the failed tx are only counted (by runSummary)
+		return check, nil
+	}
+
+	records := make([]blockcheck.FailedTx, 0)
+	for _, failedTx := range check.TxList() {
+		record := *failedTx
+		records = append(records, record)
+		logging.Log.Debugw("Tx", "block", failedTx.Block, "hash", failedTx.Hash, "status", failedTx.Status, "from", failedTx.From, "to", failedTx.To, "isFlashbots", failedTx.IsFlashbots)
+	}
+
+	// Called only from the block processor goroutine, so there are no concurrent writes
+	if blockDirWriter != nil {
+		err = blockDirWriter.Write(block.Block.NumberU64(), records)
+		if err != nil {
+			logging.Log.Errorw("Error writing block file", "block", block.Block.NumberU64(), "error", err)
+		}
+	}
+	return check, records
+}
+
+// writeOutputs writes the failed tx of a block to the NDJSON and CSV outputs
+func writeOutputs(records []blockcheck.FailedTx) {
+	for _, record := range records {
+		if ndjsonWriter != nil {
+			err := ndjsonWriter.Write(record)
+			if err != nil {
+				logging.Log.Errorw("Error writing output", "hash", record.Hash, "error", err)
+			}
+		}
+
+		if csvWriter != nil {
+			err := csvWriter.Write(record)
+			if err != nil {
+				logging.Log.Errorw("Error writing CSV", "hash", record.Hash, "error", err)
+			}
+		}
+	}
+}
+
+// flushOutputs writes the buffered failed tx of the NDJSON and CSV outputs to their files, and sets their sizes in the
+// checkpoint (before it is saved)
+func flushOutputs(checkpoint *Checkpoint, outputFile string, csvFile string) error {
+	checkpoint.OutputSizes = make(map[string]int64)
+	if ndjsonWriter != nil {
+		if err := ndjsonWriter.Flush(); err != nil {
+			return err
+		}
+		if ndjsonWriter.file != nil {
+			size, err := ndjsonWriter.file.Size()
+			if err != nil {
+				return err
+			}
+			checkpoint.OutputSizes[outputFile] = size
+		}
+	}
+	if csvWriter != nil {
+		if err := csvWriter.Flush(); err != nil {
+			return err
+		}
+		size, err := csvWriter.file.Size()
+		if err != nil {
+			return err
+		}
+		checkpoint.OutputSizes[csvFile] = size
+	}
+	return nil
+}
+
+func isFlagPassed(name string) bool {
+	found := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			found = true
+		}
+	})
+	return found
 }
@@ -0,0 +1,266 @@
+// Output of failed transactions as NDJSON (one JSON object per line, for jq or log pipelines), CSV or one JSON file
+// per block
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/metachris/flashbots/blockcheck"
+)
+
+const OutputFormatNdjson = "ndjson"
+
+// outputFile is a file that is gzip compressed if the path ends in .gz
+type outputFile struct {
+	file   *os.File
+	gzip   *gzip.Writer  // nil if not compressed
+	buffer *bufio.Writer // in front of gzip
+}
+
+func openOutputFile(path string, flag int) (*outputFile, error) {
+	file, err := os.OpenFile(path, flag, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	f := outputFile{file: file}
+	if strings.HasSuffix(path, ".gz") {
+		f.gzip = gzip.NewWriter(file)
+		f.buffer = bufio.NewWriter(f.gzip) // many small writes (one per failed tx) compress badly otherwise
+	}
+	return &f, nil
+}
+
+func (f *outputFile) Write(p []byte) (int, error) {
+	if f.gzip != nil {
+		return f.buffer.Write(p)
+	}
+	return f.file.Write(p)
+}
+
+// Flush writes the buffered data to the file (compressed files are still only complete after Close)
+func (f *outputFile) Flush() error {
+	if f.gzip == nil {
+		return nil
+	}
+	if err := f.buffer.Flush(); err != nil {
+		return err
+	}
+	return f.gzip.Flush()
+}
+
+// Size returns the size of the file (of the flushed data)
+func (f *outputFile) Size() (int64, error) {
+	info, err := f.file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// Close flushes the buffered data and closes the file. Compressed files are only complete after Close.
+func (f *outputFile) Close() error {
+	if f.gzip != nil {
+		err := f.buffer.Flush()
+		if err == nil {
+			err = f.gzip.Close()
+		}
+		if err != nil {
+			f.file.Close()
+			return err
+		}
+	}
+	return f.file.Close()
+}
+
+type NdjsonWriter struct {
+	file    *outputFile // nil if writing to stdout
+	encoder *json.Encoder
+}
+
+// NewNdjsonWriter writes to the file at path (gzip compressed if it ends in .gz), or to stdout if path is empty. The
+// file is truncated, unless appendFile is true (when resuming from a checkpoint).
+func NewNdjsonWriter(path string, appendFile bool) (*NdjsonWriter, error) {
+	var out io.Writer = os.Stdout
+	w := NdjsonWriter{}
+
+	if path != "" {
+		flag := os.O_CREATE | os.O_TRUNC | os.O_WRONLY
+		if appendFile {
+			flag = os.O_CREATE | os.O_APPEND | os.O_WRONLY
+		}
+		file, err := openOutputFile(path, flag)
+		if err != nil {
+			return nil, err
+		}
+		w.file = file
+		out = file
+	}
+
+	w.encoder = json.NewEncoder(out)
+	return &w, nil
+}
+
+func (w *NdjsonWriter) Write(record blockcheck.FailedTx) error {
+	return w.encoder.Encode(record)
+}
+
+// Flush writes the buffered records to the file
+func (w *NdjsonWriter) Flush() error {
+	if w.file == nil {
+		return nil
+	}
+	return w.file.Flush()
+}
+
+func (w *NdjsonWriter) Close() error {
+	if w.file == nil {
+		return nil
+	}
+	return w.file.Close()
+}
+
+var csvHeader = []string{"hash", "from", "to", "block", "is_flashbots", "timestamp", "status", "base_fee", "nonce", "tx_index", "bundle_id", "method_sig", "gas_price", "max_fee_per_gas", "max_priority_fee_per_gas"}
+
+type CsvWriter struct {
+	file   *outputFile
+	writer *csv.Writer
+}
+
+// NewCsvWriter appends to the file at path. The header row is only written if the file is empty, a file with another
+// header (i.e. of an older version with other columns) is an error. If path ends in .gz, the rows are gzip compressed
+// (appended as new gzip member, which gzip readers read as one stream).
+func NewCsvWriter(path string) (*CsvWriter, error) {
+	file, err := openOutputFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY)
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := file.file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	if stat.Size() > 0 {
+		header, err := readCsvHeader(path)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("reading the header of %s: %w", path, err)
+		}
+		if strings.Join(header, ",") != strings.Join(csvHeader, ",") {
+			file.Close()
+			return nil, fmt.Errorf("%s has another header (%s), wanted: %s: use a new file", path, strings.Join(header, ","), strings.Join(csvHeader, ","))
+		}
+	}
+
+	w := CsvWriter{file: file, writer: csv.NewWriter(file)}
+	if stat.Size() == 0 {
+		err = w.writer.Write(csvHeader)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+
+	return &w, nil
+}
+
+// readCsvHeader returns the first row of the CSV file at path (gzip compressed if it ends in .gz)
+func readCsvHeader(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var in io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gzipReader, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, err
+		}
+		defer gzipReader.Close()
+		in = gzipReader
+	}
+
+	reader := csv.NewReader(in)
+	reader.FieldsPerRecord = -1 // the header decides
+	return reader.Read()
+}
+
+func (w *CsvWriter) Write(record blockcheck.FailedTx) error {
+	return w.writer.Write([]string{
+		record.Hash,
+		record.From,
+		record.To,
+		strconv.FormatUint(record.Block, 10),
+		strconv.FormatBool(record.IsFlashbots),
+		strconv.FormatUint(record.Timestamp, 10),
+		record.Status,
+		record.BaseFee,
+		strconv.FormatUint(record.Nonce, 10),
+		strconv.FormatUint(uint64(record.TxIndex), 10),
+		record.BundleID,
+		record.MethodSig,
+		record.GasPrice,
+		record.MaxFeePerGas,
+		record.MaxPriorityFeePerGas,
+	})
+}
+
+// Flush writes the buffered rows to the file
+func (w *CsvWriter) Flush() error {
+	w.writer.Flush()
+	if err := w.writer.Error(); err != nil {
+		return err
+	}
+	return w.file.Flush()
+}
+
+// Close flushes the buffered rows and closes the file
+func (w *CsvWriter) Close() error {
+	w.writer.Flush()
+	if err := w.writer.Error(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
+
+// BlockDirWriter writes the failed tx of every block as JSON array to <dir>/<blockNumber>.json
+type BlockDirWriter struct {
+	dir       string
+	skipEmpty bool // don't write files for blocks without failed tx
+}
+
+// NewBlockDirWriter creates dir if it doesn't exist
+func NewBlockDirWriter(dir string, skipEmpty bool) (*BlockDirWriter, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	return &BlockDirWriter{dir: dir, skipEmpty: skipEmpty}, nil
+}
+
+func (w *BlockDirWriter) Write(blockNumber uint64, records []blockcheck.FailedTx) error {
+	if len(records) == 0 && w.skipEmpty {
+		return nil
+	}
+
+	data, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(w.dir, fmt.Sprintf("%d.json", blockNumber)), data, 0644)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/metachris/flashbots/blockcheck"
+)
+
+func TestCsvWriterHeader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "output")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"failed.csv", "failed.csv.gz"} {
+		path := filepath.Join(dir, name)
+
+		// The header is written once, and rows are appended to a file with the same header
+		for i := 0; i < 2; i++ {
+			w, err := NewCsvWriter(path)
+			if err != nil {
+				t.Fatal(name, err)
+			}
+			w.Write(blockcheck.FailedTx{Hash: "0x01", Block: 100})
+			if err := w.Close(); err != nil {
+				t.Fatal(name, err)
+			}
+		}
+
+		header, err := readCsvHeader(path)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		if strings.Join(header, ",") != strings.Join(csvHeader, ",") {
+			t.Error("Wrong header of", name, ":", header, "wanted:", csvHeader)
+		}
+	}
+
+	content, _ := ioutil.ReadFile(filepath.Join(dir, "failed.csv"))
+	if lines := strings.Count(string(content), "\n"); lines != 3 {
+		t.Error("Wrong number of lines:", lines, "wanted:", 3)
+	}
+
+	// A file with another header is not appended to
+	path := filepath.Join(dir, "old.csv")
+	old := "hash,from,to,block,is_flashbots\n0x01,0xa,0xb,100,true\n"
+	ioutil.WriteFile(path, []byte(old), 0644)
+	if _, err := NewCsvWriter(path); err == nil {
+		t.Error("Expected error for a file with another header")
+	}
+	content, _ = ioutil.ReadFile(path)
+	if string(content) != old {
+		t.Error("Wrong content:", string(content), "wanted:", old)
+	}
+}
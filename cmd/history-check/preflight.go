@@ -0,0 +1,128 @@
+// Pre-flight check of the eth node and the Flashbots API before a long scan (-check)
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/metachris/flashbots/api"
+	"github.com/metachris/flashbots/common"
+	"github.com/metachris/flashbots/logging"
+)
+
+const preflightNodeTimeout = 30 * time.Second
+
+// preflightStep is the result of one step of runPreflightCheck
+type preflightStep struct {
+	name     string
+	ok       bool
+	skipped  bool
+	detail   string
+	duration time.Duration
+	exitCode int // if not ok
+}
+
+// runPreflightCheck dials the node (chain id and latest block), queries the latest block of the Flashbots API and
+// fetches the latest block with receipts, and prints a pass / fail line for every step to out. Returns the exit code
+// of the first failed step, 0 if all passed.
+func runPreflightCheck(out io.Writer, ethUri string, rpcHeaders common.RpcHeaders, chain string, flashbotsApiUrl string) (exitCode int) {
+	var client *ethclient.Client
+	var latestBlock int64
+	steps := make([]preflightStep, 0, 3)
+	run := func(name string, exitCode int, fn func() (detail string, skipped bool, err error)) bool {
+		timeStart := time.Now()
+		detail, skipped, err := fn()
+		step := preflightStep{name: name, ok: err == nil, skipped: skipped, detail: detail, duration: time.Since(timeStart), exitCode: exitCode}
+		if err != nil {
+			step.detail = err.Error()
+		}
+		steps = append(steps, step)
+		printPreflightStep(out, step)
+		return step.ok && !step.skipped
+	}
+
+	nodeOk := run("eth node", logging.ExitCodeEthNode, func() (string, bool, error) {
+		if ethUri == "" {
+			return "", false, fmt.Errorf("missing eth node uri (-eth or ETH_NODE)")
+		}
+		var err error
+		client, err = common.DialEthClient(ethUri, rpcHeaders)
+		if err != nil {
+			return "", false, err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), preflightNodeTimeout)
+		defer cancel()
+		chainId, err := client.ChainID(ctx)
+		if err != nil {
+			return "", false, err
+		}
+		latest, err := client.BlockNumber(ctx)
+		if err != nil {
+			return "", false, err
+		}
+		latestBlock = int64(latest)
+		return fmt.Sprintf("%s, chain id %s, latest block %d", common.RedactUri(ethUri), chainId, latestBlock), false, nil
+	})
+
+	run("flashbots api", logging.ExitCodeFlashbotsApi, func() (string, bool, error) {
+		if !nodeOk {
+			return "needs the eth node", true, nil
+		}
+		available, err := common.SetupChain(client, chain, flashbotsApiUrl)
+		if err != nil {
+			return "", false, err
+		}
+		if !available {
+			return "no Flashbots API for this chain", true, nil
+		}
+
+		response, err := api.GetBlocks(&api.GetBlocksOptions{Limit: 1})
+		if err != nil {
+			return "", false, err
+		}
+		return fmt.Sprintf("%s, latest block %d (%d blocks behind the node)", api.BaseUrl, response.LatestBlockNumber, latestBlock-response.LatestBlockNumber), false, nil
+	})
+
+	run("block with receipts", logging.ExitCodeEthNode, func() (string, bool, error) {
+		if !nodeOk {
+			return "needs the eth node", true, nil
+		}
+		common.ProbeBlockReceipts(client, ethUri)
+		block, err := common.GetBlockWithTxReceipts(client, latestBlock)
+		if err != nil {
+			return "", false, err
+		}
+		return fmt.Sprintf("block %d with %d tx and %d receipts", latestBlock, len(block.Block.Transactions()), len(block.TxReceipts)), false, nil
+	})
+
+	for _, step := range steps {
+		if !step.ok {
+			return step.exitCode
+		}
+	}
+	return 0
+}
+
+func printPreflightStep(out io.Writer, step preflightStep) {
+	status := "PASS"
+	if !step.ok {
+		status = "FAIL"
+	} else if step.skipped {
+		status = "SKIP"
+	}
+
+	if logging.IsJson() {
+		fields := []interface{}{"step", step.name, "status", status, "detail", step.detail, "duration", step.duration}
+		if step.ok {
+			logging.Log.Infow("Pre-flight check", fields...)
+		} else {
+			logging.Log.Errorw("Pre-flight check", fields...)
+		}
+		return
+	}
+	fmt.Fprintf(out, "%s  %-20s %s (%s)\n", status, step.name, step.detail, step.duration.Round(time.Millisecond))
+}
@@ -0,0 +1,37 @@
+// Periodic progress log for long runs (also in silent mode)
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/metachris/flashbots/logging"
+)
+
+type Progress struct {
+	Interval    int64 // log every Interval blocks (0 disables logging)
+	TotalBlocks int64
+
+	blocksDone  int64
+	timeStarted time.Time
+}
+
+func NewProgress(interval int64, totalBlocks int64) *Progress {
+	return &Progress{Interval: interval, TotalBlocks: totalBlocks, timeStarted: time.Now()}
+}
+
+// BlockDone counts a processed block, and logs the progress every Interval blocks
+func (p *Progress) BlockDone(blockNumber int64) {
+	p.blocksDone += 1
+	if p.Interval == 0 || p.blocksDone%p.Interval != 0 {
+		return
+	}
+
+	elapsed := time.Since(p.timeStarted)
+	blocksPerSec := float64(p.blocksDone) / elapsed.Seconds()
+	eta := time.Duration(float64(p.TotalBlocks-p.blocksDone) / blocksPerSec * float64(time.Second)).Round(time.Second)
+	percent := float64(p.blocksDone) / float64(p.TotalBlocks) * 100
+
+	logging.Log.Infow("Progress", "blocks", p.blocksDone, "totalBlocks", p.TotalBlocks, "percent", fmt.Sprintf("%.1f", percent),
+		"block", blockNumber, "blocksPerSec", fmt.Sprintf("%.1f", blocksPerSec), "eta", eta)
+}
@@ -0,0 +1,122 @@
+// Aggregate statistics about the failed transactions of a run
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/metachris/flashbots/blockcheck"
+)
+
+type RunSummary struct {
+	Blocks            int    `json:"blocks"`
+	Transactions      int    `json:"transactions"`
+	FailedTx          int    `json:"failedTx"`
+	FailedFlashbotsTx int    `json:"failedFlashbotsTx"`
+	FailedOther0GasTx int    `json:"failedOther0GasTx"`
+	UniqueSenders     int    `json:"uniqueSenders"`
+	MostFailuresBlock uint64 `json:"mostFailuresBlock"`
+	MostFailuresCount int    `json:"mostFailuresCount"`
+	DuplicateFailedTx int    `json:"duplicateFailedTx"` // skipped with -first-seen
+	InternalRevertTx  int    `json:"internalRevertTx"`  // successful tx with a reverted internal call (-include-internal)
+	TimedOut          bool   `json:"timedOut"`          // the -timeout was reached before all blocks were checked
+	Sample            int64  `json:"sample"`            // every Sample-th block was checked (1 without -sample)
+
+	// Estimates for all blocks of the range with -sample: the counts of the checked blocks, scaled by the blocks of the
+	// range per checked block
+	EstimatedBlocks            int64 `json:"estimatedBlocks,omitempty"`
+	EstimatedTransactions      int64 `json:"estimatedTransactions,omitempty"`
+	EstimatedFailedTx          int64 `json:"estimatedFailedTx,omitempty"`
+	EstimatedFailedFlashbotsTx int64 `json:"estimatedFailedFlashbotsTx,omitempty"`
+	EstimatedFailedOther0GasTx int64 `json:"estimatedFailedOther0GasTx,omitempty"`
+
+	senders     map[string]bool
+	rangeBlocks int64 // number of blocks of the range (checked or not), 0 if unknown (then Blocks*Sample)
+}
+
+func NewRunSummary() RunSummary {
+	return RunSummary{Sample: 1, senders: make(map[string]bool)}
+}
+
+// AddBlockCheck adds the transactions and failed transactions of a checked block
+func (s *RunSummary) AddBlockCheck(check *blockcheck.BlockCheck) {
+	s.Blocks += 1
+	s.Transactions += len(check.EthBlock.Transactions())
+	s.DuplicateFailedTx += check.DuplicateFailedTx
+
+	failedTxs := check.FailedTxList()
+	for _, failedTx := range failedTxs {
+		s.FailedTx += 1
+		if failedTx.IsFlashbots {
+			s.FailedFlashbotsTx += 1
+		} else {
+			s.FailedOther0GasTx += 1
+		}
+		s.senders[failedTx.From] = true
+	}
+	s.UniqueSenders = len(s.senders)
+
+	for _, successfulTx := range check.SuccessfulTx {
+		if successfulTx.Status == blockcheck.TxStatusInternalRevert {
+			s.InternalRevertTx += 1
+		}
+	}
+
+	if len(failedTxs) > s.MostFailuresCount {
+		s.MostFailuresCount = len(failedTxs)
+		s.MostFailuresBlock = check.EthBlock.NumberU64()
+	}
+
+	if s.Sample > 1 {
+		s.EstimatedBlocks = s.rangeBlocks
+		if s.EstimatedBlocks == 0 {
+			s.EstimatedBlocks = int64(s.Blocks) * s.Sample
+		}
+		s.EstimatedTransactions = s.estimate(s.Transactions)
+		s.EstimatedFailedTx = s.estimate(s.FailedTx)
+		s.EstimatedFailedFlashbotsTx = s.estimate(s.FailedFlashbotsTx)
+		s.EstimatedFailedOther0GasTx = s.estimate(s.FailedOther0GasTx)
+	}
+}
+
+// estimate scales a count of the checked blocks to EstimatedBlocks (rounded)
+func (s *RunSummary) estimate(count int) int64 {
+	if s.Blocks == 0 {
+		return 0
+	}
+	return (int64(count)*s.EstimatedBlocks + int64(s.Blocks)/2) / int64(s.Blocks)
+}
+
+// Print writes the summary as a table
+func (s *RunSummary) Print(out io.Writer) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	if s.Sample > 1 {
+		fmt.Fprintf(w, "Sampled:\t1 of every %d blocks, %d blocks checked (the counts below are of these blocks)\n", s.Sample, s.Blocks)
+	}
+	fmt.Fprintf(w, "Failed tx:\t%d\n", s.FailedTx)
+	fmt.Fprintf(w, "- Flashbots:\t%d\n", s.FailedFlashbotsTx)
+	fmt.Fprintf(w, "- Other 0-gas:\t%d\n", s.FailedOther0GasTx)
+	fmt.Fprintf(w, "Unique senders:\t%d\n", s.UniqueSenders)
+	if s.MostFailuresCount > 0 {
+		fmt.Fprintf(w, "Most failures:\tblock %d (%d failed tx)\n", s.MostFailuresBlock, s.MostFailuresCount)
+	}
+	if s.TimedOut {
+		fmt.Fprintf(w, "Timed out:\tyes, only %d blocks checked\n", s.Blocks)
+	}
+	if s.InternalRevertTx > 0 {
+		fmt.Fprintf(w, "Internal reverts:\t%d\n", s.InternalRevertTx)
+	}
+	if blockcheck.DeduplicateFailedTx {
+		fmt.Fprintf(w, "Duplicates skipped:\t%d\n", s.DuplicateFailedTx)
+	}
+	if s.Sample > 1 {
+		fmt.Fprintf(w, "Estimated failed tx:\t~%d in ~%d blocks (Flashbots ~%d, other 0-gas ~%d)\n", s.EstimatedFailedTx, s.EstimatedBlocks, s.EstimatedFailedFlashbotsTx, s.EstimatedFailedOther0GasTx)
+	}
+	w.Flush()
+}
+
+func (s *RunSummary) PrintJson(out io.Writer) error {
+	return json.NewEncoder(out).Encode(s)
+}
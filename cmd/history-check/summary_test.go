@@ -0,0 +1,55 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/metachris/flashbots/blockcheck"
+)
+
+// newTestCheck returns a BlockCheck of a block with two tx, the first one failed
+func newTestCheck(number int64) *blockcheck.BlockCheck {
+	to := ethcommon.HexToAddress("0x7a250d5630B4cF539739dF2C5dAcb4c659F2488D")
+	txs := []*types.Transaction{
+		types.NewTransaction(uint64(number), to, big.NewInt(0), 100_000, big.NewInt(0), []byte{1}),
+		types.NewTransaction(uint64(number)+1, to, big.NewInt(0), 100_000, big.NewInt(0), []byte{1}),
+	}
+	block := types.NewBlock(&types.Header{Number: big.NewInt(number)}, txs, nil, nil, trie.NewStackTrie(nil))
+	failedTx := &blockcheck.FailedTx{Hash: txs[0].Hash().String(), From: "0xa", Block: uint64(number)}
+	return &blockcheck.BlockCheck{EthBlock: block, FailedTx: map[string]*blockcheck.FailedTx{failedTx.Hash: failedTx}}
+}
+
+func TestRunSummaryEstimates(t *testing.T) {
+	// Blocks 1000 to 1249 with -sample 100: 1000, 1100 and 1200 are checked
+	summary := NewRunSummary()
+	summary.Sample = 100
+	summary.rangeBlocks = 250
+	for _, block := range []int64{1000, 1100, 1200} {
+		summary.AddBlockCheck(newTestCheck(block))
+	}
+
+	if summary.Blocks != 3 || summary.FailedTx != 3 {
+		t.Fatal("Wrong counts:", summary.Blocks, summary.FailedTx, "wanted:", 3, 3)
+	}
+	for name, c := range map[string][2]int64{
+		"blocks":       {summary.EstimatedBlocks, 250},
+		"transactions": {summary.EstimatedTransactions, 500},
+		"failed tx":    {summary.EstimatedFailedTx, 250},
+		"other 0-gas":  {summary.EstimatedFailedOther0GasTx, 250},
+	} {
+		if c[0] != c[1] {
+			t.Error("Wrong estimated", name, ":", c[0], "wanted:", c[1])
+		}
+	}
+
+	// Without the range (i.e. only the sample), the counts are multiplied by the sample
+	summary = NewRunSummary()
+	summary.Sample = 100
+	summary.AddBlockCheck(newTestCheck(1000))
+	if summary.EstimatedBlocks != 100 || summary.EstimatedFailedTx != 100 {
+		t.Error("Wrong estimates:", summary.EstimatedBlocks, summary.EstimatedFailedTx, "wanted:", 100, 100)
+	}
+}
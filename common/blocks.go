@@ -0,0 +1,153 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/metachris/flashbots/logging"
+	"github.com/metachris/go-ethutils/blockswithtx"
+)
+
+// Number of attempts to get a block with receipts, and delay before the first retry (doubled after every attempt)
+var GetBlockRetryAttempts int = 3
+var GetBlockRetryDelay time.Duration = 1 * time.Second
+
+// retryWithBackoff calls fn until it succeeds or the attempts are used up, and returns the last error
+func retryWithBackoff(attempts int, delay time.Duration, fn func(attempt int) error) (err error) {
+	for attempt := 1; attempt <= attempts; attempt++ {
+		err = fn(attempt)
+		if err == nil || attempt == attempts {
+			break
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+	return err
+}
+
+// GetBlockWithTxReceipts is blockswithtx.GetBlockWithTxReceipts with retries on (transient) node errors. If the node
+// supports eth_getBlockReceipts (ProbeBlockReceipts), the receipts are fetched with one request.
+func GetBlockWithTxReceipts(client *ethclient.Client, height int64) (block *blockswithtx.BlockWithTxReceipts, err error) {
+	rpcClient := blockReceiptsClient(client)
+	err = retryWithBackoff(GetBlockRetryAttempts, GetBlockRetryDelay, func(attempt int) error {
+		if rpcClient != nil {
+			block, err = getBlockWithBlockReceipts(rpcClient, func() (*types.Block, error) {
+				return client.BlockByNumber(context.Background(), big.NewInt(height))
+			})
+		} else {
+			block, err = blockswithtx.GetBlockWithTxReceipts(client, height)
+		}
+		if err != nil && attempt < GetBlockRetryAttempts {
+			logging.Log.Warnw("GetBlockWithTxReceipts error, retrying", "block", height, "attempt", attempt, "error", err)
+		}
+		return err
+	})
+	return block, err
+}
+
+// GetBlockWithTxReceiptsByHash returns the block with the given hash and receipts for all its transactions (with
+// retries, and with one eth_getBlockReceipts request if the node supports it)
+func GetBlockWithTxReceiptsByHash(client *ethclient.Client, hash ethcommon.Hash) (block *blockswithtx.BlockWithTxReceipts, err error) {
+	rpcClient := blockReceiptsClient(client)
+	err = retryWithBackoff(GetBlockRetryAttempts, GetBlockRetryDelay, func(attempt int) error {
+		if rpcClient != nil {
+			block, err = getBlockWithBlockReceipts(rpcClient, func() (*types.Block, error) {
+				return client.BlockByHash(context.Background(), hash)
+			})
+			if err != nil && attempt < GetBlockRetryAttempts {
+				logging.Log.Warnw("GetBlockWithTxReceiptsByHash error, retrying", "hash", hash.Hex(), "attempt", attempt, "error", err)
+			}
+			return err
+		}
+
+		block = &blockswithtx.BlockWithTxReceipts{TxReceipts: make(map[ethcommon.Hash]*types.Receipt)}
+		block.Block, err = client.BlockByHash(context.Background(), hash)
+		if err == nil {
+			for _, tx := range block.Block.Transactions() {
+				receipt, txErr := client.TransactionReceipt(context.Background(), tx.Hash())
+				if errors.Is(txErr, ethereum.NotFound) {
+					continue
+				} else if txErr != nil {
+					err = txErr
+					break
+				}
+				block.TxReceipts[tx.Hash()] = receipt
+			}
+		}
+		if err != nil && attempt < GetBlockRetryAttempts {
+			logging.Log.Warnw("GetBlockWithTxReceiptsByHash error, retrying", "hash", hash.Hex(), "attempt", attempt, "error", err)
+		}
+		return err
+	})
+	return block, err
+}
+
+// GetBlocksWithTxReceipts downloads a range of blocks with tx receipts (with retries), and sends each to blockChan.
+// Blocks that still fail after all retries are logged and skipped.
+func GetBlocksWithTxReceipts(client *ethclient.Client, blockChan chan<- *blockswithtx.BlockWithTxReceipts, startBlock int64, endBlock int64, concurrency int) {
+	GetBlocksWithTxReceiptsContext(context.Background(), client, blockChan, startBlock, endBlock, concurrency)
+}
+
+// GetBlocksWithTxReceiptsContext works like GetBlocksWithTxReceipts, but stops fetching new blocks when ctx is done.
+// The blocks that are already being fetched are still sent to blockChan.
+func GetBlocksWithTxReceiptsContext(ctx context.Context, client *ethclient.Client, blockChan chan<- *blockswithtx.BlockWithTxReceipts, startBlock int64, endBlock int64, concurrency int) {
+	getBlocksWithTxReceipts(ctx, client, blockChan, concurrency, func(blockHeightChan chan<- int64) {
+		for currentBlockNumber := startBlock; currentBlockNumber <= endBlock; currentBlockNumber++ {
+			select {
+			case blockHeightChan <- currentBlockNumber:
+			case <-ctx.Done():
+				return
+			}
+		}
+	})
+}
+
+// GetBlockListWithTxReceiptsContext works like GetBlocksWithTxReceiptsContext, for a list of (not necessarily
+// contiguous) block heights
+func GetBlockListWithTxReceiptsContext(ctx context.Context, client *ethclient.Client, blockChan chan<- *blockswithtx.BlockWithTxReceipts, heights []int64, concurrency int) {
+	getBlocksWithTxReceipts(ctx, client, blockChan, concurrency, func(blockHeightChan chan<- int64) {
+		for _, height := range heights {
+			select {
+			case blockHeightChan <- height:
+			case <-ctx.Done():
+				return
+			}
+		}
+	})
+}
+
+// getBlocksWithTxReceipts downloads the blocks that feed sends, with concurrency workers, and sends each to blockChan
+func getBlocksWithTxReceipts(ctx context.Context, client *ethclient.Client, blockChan chan<- *blockswithtx.BlockWithTxReceipts, concurrency int, feed func(blockHeightChan chan<- int64)) {
+	var blockWorkerWg sync.WaitGroup
+	blockHeightChan := make(chan int64, 100) // blockHeight to fetch with receipts
+
+	for w := 1; w <= concurrency; w++ {
+		blockWorkerWg.Add(1)
+
+		go func() {
+			defer blockWorkerWg.Done()
+			for blockHeight := range blockHeightChan {
+				if ctx.Err() != nil { // skip the queued blocks
+					continue
+				}
+				block, err := GetBlockWithTxReceipts(client, blockHeight)
+				if err != nil {
+					logging.Log.Errorw("Error getting block with tx receipts, skipping block", "block", blockHeight, "error", err)
+					continue
+				}
+				blockChan <- block
+			}
+		}()
+	}
+
+	feed(blockHeightChan)
+	close(blockHeightChan)
+	blockWorkerWg.Wait()
+}
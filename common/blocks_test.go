@@ -0,0 +1,51 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/metachris/go-ethutils/blockswithtx"
+)
+
+func TestRetryWithBackoff(t *testing.T) {
+	errTransient := errors.New("transient")
+
+	calls := 0
+	err := retryWithBackoff(3, 0, func(attempt int) error {
+		calls += 1
+		if attempt < 2 {
+			return errTransient
+		}
+		return nil
+	})
+	if err != nil || calls != 2 {
+		t.Error("Unexpected result of retryWithBackoff:", err, "calls:", calls, "wanted: <nil> calls: 2")
+	}
+
+	calls = 0
+	err = retryWithBackoff(3, 0, func(attempt int) error {
+		calls += 1
+		return errTransient
+	})
+	if err != errTransient || calls != 3 {
+		t.Error("Unexpected result of retryWithBackoff:", err, "calls:", calls, "wanted: transient calls: 3")
+	}
+}
+
+func TestGetBlockListWithTxReceipts(t *testing.T) {
+	server, requests, _ := newTestNode(t, false)
+	defer server.Close()
+
+	client, err := DialEthClient(server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blockChan := make(chan *blockswithtx.BlockWithTxReceipts, 10)
+	GetBlockListWithTxReceiptsContext(context.Background(), client, blockChan, []int64{13_000_000, 12_000_000, 14_000_000}, 2)
+	close(blockChan)
+	if len(blockChan) != 3 || requests["eth_getBlockByNumber"] != 3 {
+		t.Error("Wrong number of blocks:", len(blockChan), "requests:", requests["eth_getBlockByNumber"], "wanted:", 3)
+	}
+}
@@ -0,0 +1,132 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/metachris/flashbots/api"
+	"github.com/metachris/flashbots/logging"
+)
+
+// Chain has the block explorer, Etherscan API and Flashbots blocks API of a network. FlashbotsApiUrl is empty if there
+// is none.
+type Chain struct {
+	Name            string
+	ChainId         int64
+	ExplorerUrl     string
+	EtherscanApiUrl string
+	FlashbotsApiUrl string
+}
+
+var Chains = map[int64]Chain{
+	1:        {Name: "mainnet", ChainId: 1, ExplorerUrl: "https://etherscan.io", EtherscanApiUrl: "https://api.etherscan.io", FlashbotsApiUrl: "https://blocks.flashbots.net"},
+	5:        {Name: "goerli", ChainId: 5, ExplorerUrl: "https://goerli.etherscan.io", EtherscanApiUrl: "https://api-goerli.etherscan.io"},
+	11155111: {Name: "sepolia", ChainId: 11155111, ExplorerUrl: "https://sepolia.etherscan.io", EtherscanApiUrl: "https://api-sepolia.etherscan.io"},
+}
+
+// ExplorerUrl is the block explorer used for links to blocks, transactions and addresses
+var ExplorerUrl string = Chains[1].ExplorerUrl
+
+// EtherscanApiUrl is the Etherscan API of the chain (empty for unknown chains)
+var EtherscanApiUrl string = Chains[1].EtherscanApiUrl
+
+// ChainId is the id of the chain set up by SetupChain (0 before)
+var ChainId int64
+
+// ExplorerTxBaseUrl is prepended to the hash for tx links instead of ExplorerUrl if not empty (SetExplorerTxBaseUrl)
+var ExplorerTxBaseUrl string
+
+func ExplorerTxUrl(hash string) string {
+	if ExplorerTxBaseUrl != "" {
+		return ExplorerTxBaseUrl + hash
+	}
+	return ExplorerUrl + "/tx/" + hash
+}
+
+func ExplorerAddressUrl(address string) string {
+	return ExplorerUrl + "/address/" + address
+}
+
+func ExplorerBlockUrl(blockNumber int64) string {
+	return fmt.Sprintf("%s/block/%d", ExplorerUrl, blockNumber)
+}
+
+// SetExplorerTxBaseUrl overrides the tx links of the chain explorer with baseUrl + hash (call it after SetupChain). If
+// baseUrl ends in /tx/ (i.e. "https://etherscan.io/tx/"), the links to addresses and blocks use that explorer too.
+func SetExplorerTxBaseUrl(baseUrl string) error {
+	u, err := url.Parse(baseUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid explorer URL: %s", baseUrl)
+	}
+
+	ExplorerTxBaseUrl = baseUrl
+	if strings.HasSuffix(baseUrl, "/tx/") {
+		ExplorerUrl = strings.TrimSuffix(baseUrl, "/tx/")
+	}
+	return nil
+}
+
+// GetChainByName returns a chain by name (i.e. "sepolia") or chain id (i.e. "11155111")
+func GetChainByName(name string) (chain Chain, found bool) {
+	if chainId, err := strconv.ParseInt(name, 10, 64); err == nil {
+		chain, found = Chains[chainId]
+		return chain, found
+	}
+
+	for _, chain := range Chains {
+		if chain.Name == name {
+			return chain, true
+		}
+	}
+	return chain, false
+}
+
+// ErrEthNode is wrapped by errors of eth node requests, to tell them apart from errors caused by invalid arguments
+var ErrEthNode = errors.New("eth node error")
+
+// SetupChain sets the explorer and Flashbots API URLs for the chain given by name (or of the node if name is empty).
+// flashbotsApiUrl overrides the Flashbots API of the chain if not empty. Returns false if no Flashbots API is available.
+func SetupChain(client EthClient, name string, flashbotsApiUrl string) (flashbotsApiAvailable bool, err error) {
+	var chain Chain
+	var found bool
+	if name != "" {
+		chain, found = GetChainByName(name)
+		if !found {
+			return false, fmt.Errorf("unknown chain: %s", name)
+		}
+	} else {
+		chainId, err := client.ChainID(context.Background())
+		if err != nil {
+			return false, fmt.Errorf("%w: %v", ErrEthNode, err)
+		}
+		chain, found = Chains[chainId.Int64()]
+		if !found {
+			logging.Log.Warnw("Unknown chain, Flashbots classification may be unavailable", "chainId", chainId)
+			chain = Chain{Name: "unknown", ChainId: chainId.Int64(), ExplorerUrl: ExplorerUrl}
+		}
+	}
+
+	ChainId = chain.ChainId
+	ExplorerUrl = chain.ExplorerUrl
+	EtherscanApiUrl = chain.EtherscanApiUrl
+	if flashbotsApiUrl == "" {
+		flashbotsApiUrl = chain.FlashbotsApiUrl
+	}
+
+	if flashbotsApiUrl == "" {
+		logging.Log.Warnw("No Flashbots API for this chain, failed tx are not classified as Flashbots tx", "chain", chain.Name)
+		return false, nil
+	}
+
+	err = api.SetBaseUrl(flashbotsApiUrl)
+	if err != nil {
+		return false, err
+	}
+
+	logging.Log.Infow("Using chain", "chain", chain.Name, "chainId", chain.ChainId, "explorer", ExplorerUrl, "flashbotsApi", api.BaseUrl)
+	return true, nil
+}
@@ -0,0 +1,103 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+// testChainIdClient is a fake EthClient that only implements ChainID
+type testChainIdClient struct {
+	EthClient
+	chainId int64
+	err     error
+}
+
+func (c testChainIdClient) ChainID(ctx context.Context) (*big.Int, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return big.NewInt(c.chainId), nil
+}
+
+func TestGetChainByName(t *testing.T) {
+	for _, name := range []string{"sepolia", "11155111"} {
+		chain, found := GetChainByName(name)
+		if !found || chain.ExplorerUrl != "https://sepolia.etherscan.io" {
+			t.Error("Wrong chain for", name, ":", chain, found)
+		}
+	}
+
+	if _, found := GetChainByName("unknown"); found {
+		t.Error("Should not find chain unknown")
+	}
+
+	if ExplorerTxUrl("0x123") != "https://etherscan.io/tx/0x123" {
+		t.Error("Wrong ExplorerTxUrl:", ExplorerTxUrl("0x123"))
+	}
+}
+
+func TestSetupChain(t *testing.T) {
+	defer func() {
+		ExplorerUrl = Chains[1].ExplorerUrl
+		EtherscanApiUrl = Chains[1].EtherscanApiUrl
+	}()
+
+	// Chain of the node, without Flashbots API
+	flashbotsApiAvailable, err := SetupChain(testChainIdClient{chainId: 5}, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flashbotsApiAvailable {
+		t.Error("Flashbots API should not be available on goerli")
+	}
+	if ExplorerUrl != "https://goerli.etherscan.io" {
+		t.Error("Wrong ExplorerUrl:", ExplorerUrl, "wanted:", "https://goerli.etherscan.io")
+	}
+	if EtherscanApiUrl != "https://api-goerli.etherscan.io" {
+		t.Error("Wrong EtherscanApiUrl:", EtherscanApiUrl, "wanted:", "https://api-goerli.etherscan.io")
+	}
+
+	if _, err = SetupChain(testChainIdClient{chainId: 5}, "unknown", ""); err == nil || errors.Is(err, ErrEthNode) {
+		t.Error("Expected an argument error for an unknown chain name:", err)
+	}
+
+	if _, err = SetupChain(testChainIdClient{err: errors.New("connection refused")}, "", ""); !errors.Is(err, ErrEthNode) {
+		t.Error("Expected ErrEthNode if the node request fails:", err)
+	}
+}
+
+func TestSetExplorerTxBaseUrl(t *testing.T) {
+	defer func() {
+		ExplorerUrl = Chains[1].ExplorerUrl
+		ExplorerTxBaseUrl = ""
+	}()
+
+	if err := SetExplorerTxBaseUrl("etherscan.io/tx/"); err == nil {
+		t.Error("Expected error for URL without scheme")
+	}
+
+	// Explorer with the Etherscan URL layout: all links use it
+	if err := SetExplorerTxBaseUrl("https://explorer.example.org/tx/"); err != nil {
+		t.Fatal(err)
+	}
+	if url := ExplorerTxUrl("0x123"); url != "https://explorer.example.org/tx/0x123" {
+		t.Error("Wrong ExplorerTxUrl:", url, "wanted:", "https://explorer.example.org/tx/0x123")
+	}
+	if url := ExplorerAddressUrl("0xabc"); url != "https://explorer.example.org/address/0xabc" {
+		t.Error("Wrong ExplorerAddressUrl:", url, "wanted:", "https://explorer.example.org/address/0xabc")
+	}
+
+	// Other layout: only the tx links
+	ExplorerUrl = Chains[1].ExplorerUrl
+	if err := SetExplorerTxBaseUrl("https://example.org/transaction?hash="); err != nil {
+		t.Fatal(err)
+	}
+	if url := ExplorerTxUrl("0x123"); url != "https://example.org/transaction?hash=0x123" {
+		t.Error("Wrong ExplorerTxUrl:", url, "wanted:", "https://example.org/transaction?hash=0x123")
+	}
+	if ExplorerUrl != Chains[1].ExplorerUrl {
+		t.Error("Wrong ExplorerUrl:", ExplorerUrl, "wanted:", Chains[1].ExplorerUrl)
+	}
+}
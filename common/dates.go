@@ -0,0 +1,69 @@
+// Parsing of date arguments (i.e. -start and -end of history-check)
+package common
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateArgLayouts are the accepted formats of dates without timezone, in the timezone given to ParseDateArg
+var dateArgLayouts = []string{"2006-01-02", "2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02T15:04:05", "2006-01-02 15:04:05"}
+
+// ParseDateArg parses a date argument and returns it in UTC:
+// - an RFC3339 timestamp with timezone (i.e. 2023-05-01T14:30:00Z or 2023-05-01T16:30:00+02:00)
+// - a date (yyyy-mm-dd), optionally with time (yyyy-mm-ddThh:mm[:ss] or "yyyy-mm-dd hh:mm[:ss]"), in loc (UTC if nil)
+// - a unix timestamp with @ prefix, i.e. the timestamp of a block (@1620000000)
+// - a time relative to now like -1d12h (units: w, d, h, m, s)
+// Times in loc that don't exist or are ambiguous because of a daylight saving time change are an error.
+func ParseDateArg(s string, loc *time.Location) (t time.Time, err error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	if strings.HasPrefix(s, "-") {
+		offsetSec, err := TimeStringToSec(s)
+		if err != nil {
+			return t, err
+		}
+		return time.Now().UTC().Add(time.Duration(offsetSec) * time.Second), nil
+	}
+
+	if strings.HasPrefix(s, "@") {
+		sec, err := strconv.ParseInt(s[1:], 10, 64)
+		if err != nil || sec < 0 {
+			return t, fmt.Errorf("invalid unix timestamp %s", s)
+		}
+		return time.Unix(sec, 0).UTC(), nil
+	}
+
+	if t, err = time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC(), nil
+	}
+
+	for _, layout := range dateArgLayouts {
+		t, err = time.ParseInLocation(layout, s, loc)
+		if err == nil {
+			return t.UTC(), checkWallClock(t, layout, s, loc)
+		}
+	}
+	return t, fmt.Errorf("invalid date %s (use yyyy-mm-dd, yyyy-mm-ddThh:mm, RFC3339 like 2023-05-01T14:30:00Z, a unix timestamp like @1620000000 or a relative time like -1d12h)", s)
+}
+
+// checkWallClock returns an error if the time s of layout doesn't exist in loc (skipped when the clocks are set
+// forward), or exists twice (when the clocks are set back). t is the parsed time.
+func checkWallClock(t time.Time, layout string, s string, loc *time.Location) error {
+	if t.Format(layout) != s {
+		return fmt.Errorf("date %s doesn't exist in %s (daylight saving time change), use an RFC3339 timestamp with offset", s, loc)
+	}
+
+	_, offsetBefore := t.Add(-3 * time.Hour).Zone()
+	_, offsetAfter := t.Add(3 * time.Hour).Zone()
+	if shift := time.Duration(offsetBefore-offsetAfter) * time.Second; shift > 0 {
+		if t.Add(shift).Format(layout) == s || t.Add(-shift).Format(layout) == s {
+			return fmt.Errorf("date %s is ambiguous in %s (daylight saving time change), use an RFC3339 timestamp with offset", s, loc)
+		}
+	}
+	return nil
+}
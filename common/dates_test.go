@@ -0,0 +1,99 @@
+package common
+
+import (
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata" // the test timezones, also without zoneinfo on the system
+)
+
+func TestParseDateArg(t *testing.T) {
+	for _, c := range []struct {
+		s        string
+		expected string // RFC3339, UTC
+	}{
+		{"2021-08-01", "2021-08-01T00:00:00Z"},
+		{"2021-08-01T12:30", "2021-08-01T12:30:00Z"},
+		{"2021-08-01 12:30:15", "2021-08-01T12:30:15Z"},
+		{"2023-05-01T14:30:00Z", "2023-05-01T14:30:00Z"},
+		{"2023-05-01T16:30:00+02:00", "2023-05-01T14:30:00Z"},
+		{"@1620000000", "2021-05-03T00:00:00Z"},
+	} {
+		parsed, err := ParseDateArg(c.s, nil)
+		if err != nil {
+			t.Error("Unexpected error for", c.s, ":", err)
+		} else if parsed.Format(time.RFC3339) != c.expected {
+			t.Error("Wrong time for", c.s, ":", parsed.Format(time.RFC3339), "wanted:", c.expected)
+		}
+	}
+
+	for _, s := range []string{"2021-13-01", "yesterday", "@abc", "-1x"} {
+		if _, err := ParseDateArg(s, nil); err == nil {
+			t.Error("Expected an error for", s)
+		}
+	}
+
+	parsed, err := ParseDateArg("-1h", nil)
+	if err != nil || time.Since(parsed) < 59*time.Minute || time.Since(parsed) > 61*time.Minute {
+		t.Error("Wrong relative time:", parsed, err)
+	}
+}
+
+func TestParseDateArgTimezone(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		s        string
+		loc      *time.Location
+		expected string // RFC3339, UTC
+	}{
+		// New York: clocks set forward on 2021-03-14 at 2:00 EST, and back on 2021-11-07 at 2:00 EDT
+		{"2021-03-14", newYork, "2021-03-14T05:00:00Z"},
+		{"2021-03-14T01:59", newYork, "2021-03-14T06:59:00Z"},
+		{"2021-03-14T03:00", newYork, "2021-03-14T07:00:00Z"},
+		{"2021-03-15", newYork, "2021-03-15T04:00:00Z"},
+		{"2021-11-07T00:59", newYork, "2021-11-07T04:59:00Z"},
+		{"2021-11-07T02:00", newYork, "2021-11-07T07:00:00Z"},
+
+		// Berlin: clocks set forward on 2021-03-28 at 2:00 CET, and back on 2021-10-31 at 3:00 CEST
+		{"2021-03-28T01:59", berlin, "2021-03-28T00:59:00Z"},
+		{"2021-03-28T03:00", berlin, "2021-03-28T01:00:00Z"},
+		{"2021-10-31T01:59", berlin, "2021-10-30T23:59:00Z"},
+		{"2021-10-31T03:00", berlin, "2021-10-31T02:00:00Z"},
+
+		// Timestamps with offset and relative times don't depend on the timezone
+		{"2023-05-01T14:30:00Z", newYork, "2023-05-01T14:30:00Z"},
+		{"@1620000000", berlin, "2021-05-03T00:00:00Z"},
+	} {
+		parsed, err := ParseDateArg(c.s, c.loc)
+		if err != nil {
+			t.Error("Unexpected error for", c.s, "in", c.loc, ":", err)
+		} else if parsed.Format(time.RFC3339) != c.expected {
+			t.Error("Wrong time for", c.s, "in", c.loc, ":", parsed.Format(time.RFC3339), "wanted:", c.expected)
+		}
+	}
+
+	// Skipped and repeated wall clock times are an error
+	for _, c := range []struct {
+		s        string
+		loc      *time.Location
+		expected string
+	}{
+		{"2021-03-14T02:30", newYork, "doesn't exist"},
+		{"2021-03-28T02:30", berlin, "doesn't exist"},
+		{"2021-11-07T01:30", newYork, "ambiguous"},
+		{"2021-10-31T02:30", berlin, "ambiguous"},
+	} {
+		_, err := ParseDateArg(c.s, c.loc)
+		if err == nil || !strings.Contains(err.Error(), c.expected) {
+			t.Error("Wrong error for", c.s, "in", c.loc, ":", err, "wanted:", c.expected)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Getting all receipts of a block with one eth_getBlockReceipts request, if the node supports it
+package common
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/metachris/flashbots/logging"
+	"github.com/metachris/go-ethutils/blockswithtx"
+)
+
+const probeBlockReceiptsTimeout = 10 * time.Second
+
+// The rpc clients of the eth clients of DialEthClient (ethclient.Client doesn't expose it), and of the ones whose node
+// supports eth_getBlockReceipts (ProbeBlockReceipts)
+var (
+	rpcClients           = make(map[*ethclient.Client]*rpc.Client)
+	blockReceiptsClients = make(map[*ethclient.Client]*rpc.Client)
+	rpcClientsLock       sync.RWMutex
+)
+
+func registerRpcClient(client *ethclient.Client, rpcClient *rpc.Client) {
+	rpcClientsLock.Lock()
+	defer rpcClientsLock.Unlock()
+	rpcClients[client] = rpcClient
+}
+
+// ProbeBlockReceipts checks once if the node of client (from DialEthClient) supports eth_getBlockReceipts. If it does,
+// GetBlockWithTxReceipts and GetBlockWithTxReceiptsByHash get all receipts of a block with one request, instead of one
+// request per tx.
+func ProbeBlockReceipts(client *ethclient.Client, uri string) bool {
+	rpcClientsLock.RLock()
+	rpcClient := rpcClients[client]
+	rpcClientsLock.RUnlock()
+	if rpcClient == nil {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), probeBlockReceiptsTimeout)
+	defer cancel()
+	var receipts []*types.Receipt
+	err := rpcClient.CallContext(ctx, &receipts, "eth_getBlockReceipts", "latest")
+	if err != nil {
+		logging.Log.Infow("Eth node doesn't support eth_getBlockReceipts, getting the receipts per tx", "node", uri, "error", err)
+		return false
+	}
+
+	rpcClientsLock.Lock()
+	defer rpcClientsLock.Unlock()
+	blockReceiptsClients[client] = rpcClient
+	logging.Log.Infow("Eth node supports eth_getBlockReceipts, getting the receipts per block", "node", uri)
+	return true
+}
+
+// blockReceiptsClient returns the rpc client of client if its node supports eth_getBlockReceipts, else nil
+func blockReceiptsClient(client *ethclient.Client) *rpc.Client {
+	rpcClientsLock.RLock()
+	defer rpcClientsLock.RUnlock()
+	return blockReceiptsClients[client]
+}
+
+// getBlockWithBlockReceipts returns the block of getBlock with its receipts from one eth_getBlockReceipts request (for
+// the hash of the block, so the receipts are of the same block after a reorg)
+func getBlockWithBlockReceipts(rpcClient *rpc.Client, getBlock func() (*types.Block, error)) (*blockswithtx.BlockWithTxReceipts, error) {
+	block, err := getBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var receipts []*types.Receipt
+	err = rpcClient.CallContext(context.Background(), &receipts, "eth_getBlockReceipts", block.Hash().Hex())
+	if err != nil {
+		return nil, err
+	}
+	if len(receipts) == 0 && len(block.Transactions()) > 0 {
+		return nil, fmt.Errorf("no receipts for block %s", block.Hash().Hex())
+	}
+
+	ret := &blockswithtx.BlockWithTxReceipts{Block: block, TxReceipts: make(map[ethcommon.Hash]*types.Receipt)}
+	for _, receipt := range receipts {
+		ret.TxReceipts[receipt.TxHash] = receipt
+	}
+	return ret, nil
+}
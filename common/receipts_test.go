@@ -0,0 +1,99 @@
+package common
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// newTestNode returns a JSON-RPC server with one block with one failed tx, that counts the requests per method
+func newTestNode(t *testing.T, supportsBlockReceipts bool) (server *httptest.Server, requests map[string]int, tx *types.Transaction) {
+	key, _ := crypto.GenerateKey()
+	to := ethcommon.HexToAddress("0x7a250d5630B4cF539739dF2C5dAcb4c659F2488D")
+	tx, err := types.SignNewTx(key, types.LatestSignerForChainID(big.NewInt(1)), &types.LegacyTx{GasPrice: big.NewInt(0), Gas: 100_000, To: &to, Data: []byte{0x38}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header := &types.Header{Number: big.NewInt(13_000_000), Difficulty: big.NewInt(0), UncleHash: types.EmptyUncleHash, TxHash: types.DeriveSha(types.Transactions{tx}, trie.NewStackTrie(nil))}
+	block := make(map[string]interface{})
+	headerJson, _ := json.Marshal(header)
+	json.Unmarshal(headerJson, &block)
+	block["transactions"] = []*types.Transaction{tx}
+	block["uncles"] = []string{}
+
+	receipt := &types.Receipt{Status: types.ReceiptStatusFailed, TxHash: tx.Hash(), GasUsed: 21_000, CumulativeGasUsed: 21_000, Logs: []*types.Log{}}
+
+	var lock sync.Mutex
+	requests = make(map[string]int)
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Id     json.RawMessage
+			Method string
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		lock.Lock()
+		requests[request.Method] += 1
+		lock.Unlock()
+
+		response := map[string]interface{}{"jsonrpc": "2.0", "id": request.Id}
+		switch {
+		case request.Method == "eth_getBlockReceipts" && supportsBlockReceipts:
+			response["result"] = []*types.Receipt{receipt}
+		case request.Method == "eth_getBlockByNumber":
+			response["result"] = block
+		case request.Method == "eth_getTransactionReceipt":
+			response["result"] = receipt
+		default:
+			response["error"] = map[string]interface{}{"code": -32601, "message": "the method " + request.Method + " does not exist/is not available"}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}))
+	return server, requests, tx
+}
+
+func TestGetBlockWithTxReceiptsBlockReceipts(t *testing.T) {
+	for _, supportsBlockReceipts := range []bool{true, false} {
+		server, requests, tx := newTestNode(t, supportsBlockReceipts)
+		defer server.Close()
+
+		client, err := DialEthClient(server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if supported := ProbeBlockReceipts(client, server.URL); supported != supportsBlockReceipts {
+			t.Error("Wrong ProbeBlockReceipts:", supported, "wanted:", supportsBlockReceipts)
+		}
+
+		block, err := GetBlockWithTxReceipts(client, 13_000_000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if receipt := block.TxReceipts[tx.Hash()]; receipt == nil || receipt.Status != types.ReceiptStatusFailed {
+			t.Error("Wrong receipt:", receipt)
+		}
+
+		// The probe, and one request per block (or one per tx without eth_getBlockReceipts)
+		wantedRequests := map[bool]map[string]int{
+			true:  {"eth_getBlockReceipts": 2, "eth_getBlockByNumber": 1},
+			false: {"eth_getBlockReceipts": 1, "eth_getBlockByNumber": 1, "eth_getTransactionReceipt": 1},
+		}[supportsBlockReceipts]
+		if len(requests) != len(wantedRequests) {
+			t.Error("Wrong requests:", requests, "wanted:", wantedRequests)
+		}
+		for method, count := range wantedRequests {
+			if requests[method] != count {
+				t.Error("Wrong number of", method, "requests:", requests[method], "wanted:", count)
+			}
+		}
+	}
+}
@@ -0,0 +1,198 @@
+// Recording the HTTP requests to the eth node (and the Flashbots API) to a JSON fixture file, to replay them in tests
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+// RpcInteraction is a recorded request with its response. JSON-RPC requests are matched by method and params (the id
+// of the response is replaced with the id of the request), other requests by path, query and body.
+type RpcInteraction struct {
+	Path      string          // path and query of the URL
+	RpcMethod string          `json:",omitempty"`
+	RpcParams json.RawMessage `json:",omitempty"`
+	Body      string          `json:",omitempty"` // of other requests than JSON-RPC
+	Status    int
+	Response  json.RawMessage `json:",omitempty"` // JSON response (without the JSON-RPC id)
+	Text      string          `json:",omitempty"` // response that isn't JSON
+}
+
+// RpcFixture is a list of recorded interactions, served by ServeHTTP
+type RpcFixture struct {
+	Interactions []RpcInteraction
+}
+
+// rpcRequest is the part of a JSON-RPC request that identifies it
+type rpcRequest struct {
+	Id      json.RawMessage `json:"id"`
+	JsonRpc string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+// newRpcInteraction returns the interaction for a request (without the response)
+func newRpcInteraction(path string, body []byte) (interaction RpcInteraction, id json.RawMessage) {
+	interaction.Path = path
+	var request rpcRequest
+	if err := json.Unmarshal(body, &request); err == nil && request.JsonRpc != "" && request.Method != "" {
+		interaction.RpcMethod = request.Method
+		interaction.RpcParams = compactJson(request.Params)
+		return interaction, request.Id
+	}
+	interaction.Body = string(body)
+	return interaction, nil
+}
+
+func (i *RpcInteraction) matches(other RpcInteraction) bool {
+	return i.Path == other.Path && i.RpcMethod == other.RpcMethod && bytes.Equal(i.RpcParams, other.RpcParams) && i.Body == other.Body
+}
+
+func compactJson(data json.RawMessage) json.RawMessage {
+	var b bytes.Buffer
+	if len(data) == 0 || json.Compact(&b, data) != nil {
+		return data
+	}
+	return b.Bytes()
+}
+
+// setResponse stores the response body, without the id of JSON-RPC responses
+func (i *RpcInteraction) setResponse(status int, body []byte) {
+	i.Status = status
+	if !json.Valid(body) {
+		i.Text = string(body)
+		return
+	}
+
+	var response map[string]json.RawMessage
+	if i.RpcMethod != "" && json.Unmarshal(body, &response) == nil {
+		delete(response, "id")
+		body, _ = json.Marshal(response)
+	}
+	i.Response = compactJson(body)
+}
+
+// responseBody returns the recorded response, with the given id for JSON-RPC responses
+func (i *RpcInteraction) responseBody(id json.RawMessage) []byte {
+	if i.Response == nil {
+		return []byte(i.Text)
+	}
+
+	var response map[string]json.RawMessage
+	if i.RpcMethod != "" && id != nil && json.Unmarshal(i.Response, &response) == nil {
+		response["id"] = id
+		body, _ := json.Marshal(response)
+		return body
+	}
+	return i.Response
+}
+
+// LoadRpcFixture reads a fixture written by RpcRecorder.Save
+func LoadRpcFixture(path string) (*RpcFixture, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	fixture := &RpcFixture{}
+	err = json.Unmarshal(data, fixture)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fixture %s: %v", path, err)
+	}
+	for i := range fixture.Interactions { // the params are indented in the file
+		fixture.Interactions[i].RpcParams = compactJson(fixture.Interactions[i].RpcParams)
+	}
+	return fixture, nil
+}
+
+// ServeHTTP replays the recorded response of the request (i.e. with httptest.NewServer). Requests that weren't
+// recorded get a 404 response.
+func (f *RpcFixture) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	request, id := newRpcInteraction(r.URL.RequestURI(), body)
+	for _, interaction := range f.Interactions {
+		if interaction.matches(request) {
+			if interaction.Response != nil {
+				w.Header().Set("Content-Type", "application/json")
+			}
+			w.WriteHeader(interaction.Status)
+			w.Write(interaction.responseBody(id))
+			return
+		}
+	}
+	http.Error(w, fmt.Sprintf("request not in fixture: %s %s%s", request.Path, request.RpcMethod, request.RpcParams), http.StatusNotFound)
+}
+
+// RpcRecorder is an http.RoundTripper that records the requests and responses of Transport (http.DefaultTransport if
+// nil). Set it as RpcTransport (eth node) and as transport of api.HttpClient (Flashbots API), then Save the fixture.
+type RpcRecorder struct {
+	Transport http.RoundTripper
+
+	lock    sync.Mutex
+	fixture RpcFixture
+}
+
+func (r *RpcRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	res, err := transport.RoundTrip(req)
+	if err != nil {
+		return res, err
+	}
+
+	resBody, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(resBody))
+
+	interaction, _ := newRpcInteraction(req.URL.RequestURI(), body)
+	interaction.setResponse(res.StatusCode, resBody)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, recorded := range r.fixture.Interactions { // keep the first response of repeated requests
+		if recorded.matches(interaction) {
+			return res, nil
+		}
+	}
+	r.fixture.Interactions = append(r.fixture.Interactions, interaction)
+	return res, nil
+}
+
+// Fixture returns the interactions recorded so far
+func (r *RpcRecorder) Fixture() *RpcFixture {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	fixture := &RpcFixture{Interactions: make([]RpcInteraction, len(r.fixture.Interactions))}
+	copy(fixture.Interactions, r.fixture.Interactions)
+	return fixture
+}
+
+// Save writes the recorded interactions to a JSON fixture file (see LoadRpcFixture)
+func (r *RpcRecorder) Save(path string) error {
+	data, err := json.MarshalIndent(r.Fixture(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,64 @@
+package common
+
+import (
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestRpcRecorder(t *testing.T) {
+	node, requests, tx := newTestNode(t, false)
+	defer node.Close()
+
+	// Record
+	recorder := &RpcRecorder{}
+	RpcTransport = recorder
+	defer func() { RpcTransport = nil }()
+
+	client, err := DialEthClient(node.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = GetBlockWithTxReceipts(client, 13_000_000); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = GetBlockWithTxReceipts(client, 13_000_000); err != nil { // repeated requests are recorded once
+		t.Fatal(err)
+	}
+	if len(recorder.Fixture().Interactions) != 2 || requests["eth_getBlockByNumber"] != 2 {
+		t.Fatal("Wrong recorded interactions:", recorder.Fixture().Interactions, "requests:", requests)
+	}
+
+	path := filepath.Join(t.TempDir(), "fixture.json")
+	if err = recorder.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// Replay
+	fixture, err := LoadRpcFixture(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(fixture)
+	defer server.Close()
+	RpcTransport = nil
+
+	client, err = DialEthClient(server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := GetBlockWithTxReceipts(client, 13_000_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block.Block.Transactions()[0].Hash() != tx.Hash() || block.TxReceipts[tx.Hash()] == nil {
+		t.Error("Wrong replayed block:", block.Block.Transactions(), block.TxReceipts)
+	}
+
+	// Requests that weren't recorded fail
+	GetBlockRetryAttempts = 1
+	defer func() { GetBlockRetryAttempts = 3 }()
+	if _, err = GetBlockWithTxReceipts(client, 13_000_001); err == nil {
+		t.Error("Expected error for a block that isn't in the fixture")
+	}
+}
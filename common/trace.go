@@ -0,0 +1,67 @@
+// Tracing the internal calls of a transaction with debug_traceTransaction (callTracer), if the node supports it
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const traceTransactionTimeout = 30 * time.Second
+
+// ErrTracingNotSupported is returned by TraceTransaction if the node doesn't have the debug API, or the client isn't
+// from DialEthClient
+var ErrTracingNotSupported = errors.New("debug_traceTransaction not supported")
+
+// CallFrame is a call of the callTracer result, with its internal calls. Error is set for reverted calls, Output has
+// the revert data.
+type CallFrame struct {
+	Type   string      `json:"type"`
+	From   string      `json:"from"`
+	To     string      `json:"to"`
+	Output string      `json:"output"`
+	Error  string      `json:"error"`
+	Calls  []CallFrame `json:"calls"`
+}
+
+// FirstRevertedCall returns the first internal call (depth-first, without f itself) with an error, nil if there is none
+func (f *CallFrame) FirstRevertedCall() *CallFrame {
+	for i := range f.Calls {
+		if f.Calls[i].Error != "" {
+			return &f.Calls[i]
+		}
+		if reverted := f.Calls[i].FirstRevertedCall(); reverted != nil {
+			return reverted
+		}
+	}
+	return nil
+}
+
+// TraceTransaction returns the call tree of the tx (debug_traceTransaction with the callTracer). Errors wrap
+// ErrTracingNotSupported if the node doesn't support it.
+func TraceTransaction(client *ethclient.Client, hash ethcommon.Hash) (*CallFrame, error) {
+	rpcClientsLock.RLock()
+	rpcClient := rpcClients[client]
+	rpcClientsLock.RUnlock()
+	if rpcClient == nil {
+		return nil, ErrTracingNotSupported
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), traceTransactionTimeout)
+	defer cancel()
+	var frame CallFrame
+	err := rpcClient.CallContext(ctx, &frame, "debug_traceTransaction", hash.Hex(), map[string]string{"tracer": "callTracer"})
+	if err != nil {
+		var rpcErr rpc.Error
+		if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == -32601 { // method not found
+			return nil, fmt.Errorf("%w: %v", ErrTracingNotSupported, err)
+		}
+		return nil, err
+	}
+	return &frame, nil
+}
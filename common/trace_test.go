@@ -0,0 +1,37 @@
+package common
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFirstRevertedCall(t *testing.T) {
+	frame := CallFrame{Type: "CALL", To: "0x1", Calls: []CallFrame{
+		{Type: "STATICCALL", To: "0x2"},
+		{Type: "CALL", To: "0x3", Calls: []CallFrame{{Type: "DELEGATECALL", To: "0x4", Error: "execution reverted"}}},
+		{Type: "CALL", To: "0x5", Error: "out of gas"},
+	}}
+	reverted := frame.FirstRevertedCall()
+	if reverted == nil || reverted.To != "0x4" {
+		t.Error("Wrong FirstRevertedCall:", reverted, "wanted:", "0x4")
+	}
+
+	frame = CallFrame{Type: "CALL", To: "0x1", Error: "execution reverted"} // only the internal calls count
+	if reverted := frame.FirstRevertedCall(); reverted != nil {
+		t.Error("Wrong FirstRevertedCall:", reverted, "wanted:", nil)
+	}
+}
+
+func TestTraceTransactionNotSupported(t *testing.T) {
+	server, _, tx := newTestNode(t, false)
+	defer server.Close()
+
+	client, err := DialEthClient(server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = TraceTransaction(client, tx.Hash())
+	if !errors.Is(err, ErrTracingNotSupported) {
+		t.Error("Wrong TraceTransaction error:", err, "wanted:", ErrTracingNotSupported)
+	}
+}
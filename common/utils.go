@@ -3,7 +3,6 @@ package common
 import (
 	"bytes"
 	"context"
-	"errors"
 	"fmt"
 	"log"
 	"math/big"
@@ -47,33 +46,59 @@ func BigIntToEString(i *big.Int, prec int) string {
 	return BigFloatToEString(f, prec)
 }
 
+// EthStringToWei converts an amount in ETH (i.e. "0.5") to wei
+func EthStringToWei(s string) (*big.Int, error) {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok || r.Sign() < 0 {
+		return nil, fmt.Errorf("invalid ETH amount: %s", s)
+	}
+
+	r.Mul(r, new(big.Rat).SetInt(big.NewInt(1e18)))
+	return new(big.Int).Quo(r.Num(), r.Denom()), nil
+}
+
+var timespanUnitSec = map[byte]int{'w': 7 * 24 * 60 * 60, 'd': 24 * 60 * 60, 'h': 60 * 60, 'm': 60, 's': 1}
+
+// TimeStringToSec converts a timespan like "30m", "-1d" or "-1d12h" (units: w, d, h, m, s) to seconds
 func TimeStringToSec(s string) (timespanSec int, err error) {
+	input := s
 	isNegativeNumber := strings.HasPrefix(s, "-")
 	if isNegativeNumber {
 		s = s[1:]
 	}
+	if s == "" {
+		return 0, fmt.Errorf("invalid timespan '%s': missing number and unit", input)
+	}
 
-	var sec int = 0
-	switch {
-	case strings.HasSuffix(s, "s"):
-		sec, err = strconv.Atoi(strings.TrimSuffix(s, "s"))
-	case strings.HasSuffix(s, "m"):
-		sec, err = strconv.Atoi(strings.TrimSuffix(s, "m"))
-		sec *= 60
-	case strings.HasSuffix(s, "h"):
-		sec, err = strconv.Atoi(strings.TrimSuffix(s, "h"))
-		sec *= 60 * 60
-	case strings.HasSuffix(s, "d"):
-		sec, err = strconv.Atoi(strings.TrimSuffix(s, "d"))
-		sec *= 60 * 60 * 24
-	default:
-		err = errors.New("couldn't detect time format")
+	for s != "" {
+		numDigits := 0
+		for numDigits < len(s) && s[numDigits] >= '0' && s[numDigits] <= '9' {
+			numDigits++
+		}
+		if numDigits == 0 {
+			return 0, fmt.Errorf("invalid timespan '%s': expected a number at '%s'", input, s)
+		}
+		if numDigits == len(s) {
+			return 0, fmt.Errorf("invalid timespan '%s': missing unit after %s (w, d, h, m or s)", input, s)
+		}
+
+		n, err := strconv.Atoi(s[:numDigits])
+		if err != nil {
+			return 0, fmt.Errorf("invalid timespan '%s': %w", input, err)
+		}
+		unitSec, found := timespanUnitSec[s[numDigits]]
+		if !found {
+			return 0, fmt.Errorf("invalid timespan '%s': unknown unit '%c' (w, d, h, m or s)", input, s[numDigits])
+		}
+
+		timespanSec += n * unitSec
+		s = s[numDigits+1:]
 	}
+
 	if isNegativeNumber {
-		sec *= -1 // make negative
+		timespanSec *= -1 // make negative
 	}
-
-	return sec, err
+	return timespanSec, nil
 }
 
 func TxToRlp(tx *types.Transaction) string {
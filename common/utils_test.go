@@ -63,3 +63,51 @@ func TestBigIntToEString(t *testing.T) {
 		t.Error("Unexpected result string from BigFloatToEString:", s)
 	}
 }
+
+func TestEthStringToWei(t *testing.T) {
+	wei, err := EthStringToWei("1.5")
+	if err != nil || wei.String() != "1500000000000000000" {
+		t.Error("Unexpected result from EthStringToWei:", wei, err)
+	}
+
+	wei, err = EthStringToWei("0.000000000000000001")
+	if err != nil || wei.String() != "1" {
+		t.Error("Unexpected result from EthStringToWei:", wei, err)
+	}
+
+	_, err = EthStringToWei("-1")
+	if err == nil {
+		t.Error("Expected error from EthStringToWei for negative amount")
+	}
+
+	_, err = EthStringToWei("abc")
+	if err == nil {
+		t.Error("Expected error from EthStringToWei for invalid amount")
+	}
+}
+
+func TestTimeStringToSec(t *testing.T) {
+	expected := map[string]int{
+		"30s":    30,
+		"-1d":    -86400,
+		"-6h":    -21600,
+		"-90m":   -5400,
+		"-2w":    -1209600,
+		"-1d12h": -129600,
+		"1w2d3h": 788400,
+	}
+	for s, want := range expected {
+		got, err := TimeStringToSec(s)
+		if err != nil {
+			t.Error("Unexpected error for", s, ":", err)
+		} else if got != want {
+			t.Error("Wrong seconds for", s, ":", got, "wanted:", want)
+		}
+	}
+
+	for _, s := range []string{"", "-", "-d", "-1", "-1x", "-1d12", "1.5h", "-1d-2h"} {
+		if _, err := TimeStringToSec(s); err == nil {
+			t.Error("Expected an error for", s)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+// Version of the build, for the startup log and the /version endpoint
+package common
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Version, GitCommit and BuildDate are set at build time, i.e. with
+// -ldflags "-X github.com/metachris/flashbots/common.Version=v1.2.3 -X github.com/metachris/flashbots/common.GitCommit=$(git rev-parse HEAD) -X github.com/metachris/flashbots/common.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	Version   string
+	GitCommit string
+	BuildDate string
+)
+
+// BuildInfo describes the running build (served at /version)
+type BuildInfo struct {
+	Version   string `json:"version"`
+	GitCommit string `json:"gitCommit"`
+	BuildDate string `json:"buildDate"`
+	GoVersion string `json:"goVersion"`
+}
+
+// BuildVersion returns Version, or else the module version of the build ("(devel)" for builds from a checkout)
+func BuildVersion() string {
+	if Version != "" {
+		return Version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "unknown"
+}
+
+// GetBuildInfo returns the version of the build, and "unknown" for GitCommit and BuildDate if they weren't set
+func GetBuildInfo() BuildInfo {
+	info := BuildInfo{Version: BuildVersion(), GitCommit: GitCommit, BuildDate: BuildDate, GoVersion: runtime.Version()}
+	if info.GitCommit == "" {
+		info.GitCommit = "unknown"
+	}
+	if info.BuildDate == "" {
+		info.BuildDate = "unknown"
+	}
+	return info
+}
@@ -0,0 +1,22 @@
+package common
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestGetBuildInfo(t *testing.T) {
+	defer func() { Version, GitCommit, BuildDate = "", "", "" }()
+
+	info := GetBuildInfo()
+	if info.GitCommit != "unknown" || info.BuildDate != "unknown" || info.GoVersion != runtime.Version() {
+		t.Error("Wrong build info without ldflags:", info)
+	}
+
+	Version, GitCommit, BuildDate = "v1.2.3", "abc123", "2021-08-01T12:00:00Z"
+	info = GetBuildInfo()
+	expected := BuildInfo{Version: "v1.2.3", GitCommit: "abc123", BuildDate: "2021-08-01T12:00:00Z", GoVersion: runtime.Version()}
+	if info != expected {
+		t.Error("Wrong build info:", info, "wanted:", expected)
+	}
+}
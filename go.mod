@@ -5,9 +5,16 @@ go 1.16
 require (
 	github.com/btcsuite/btcd v0.22.0-beta // indirect
 	github.com/ethereum/go-ethereum v1.10.7
+	github.com/gdamore/tcell/v2 v2.3.3
+	github.com/gorilla/websocket v1.4.2
+	github.com/lib/pq v1.10.2
+	github.com/mattn/go-sqlite3 v1.14.8
 	github.com/metachris/flashbots-rpc v0.1.2
 	github.com/metachris/go-ethutils v0.4.7
 	github.com/pkg/errors v0.9.1
+	github.com/prometheus/client_golang v1.11.0
+	github.com/rivo/tview v0.0.0-20210624165335-29d673af0ce2
+	go.uber.org/zap v1.19.1
 	golang.org/x/crypto v0.0.0-20210813211128-0a44fdfbc16e // indirect
 	golang.org/x/sys v0.0.0-20210816183151-1e6c022a8912 // indirect
 )
@@ -0,0 +1,22 @@
+// Exit codes of the commands, to tell failure classes apart in scripts
+package logging
+
+import "os"
+
+const (
+	ExitCodeError        = 1 // any other error
+	ExitCodeInvalidArgs  = 2 // invalid or missing arguments (also config and input files)
+	ExitCodeEthNode      = 3 // connecting to or querying the eth node failed
+	ExitCodeFlashbotsApi = 4 // querying the Flashbots API failed
+)
+
+// osExit is replaced in tests
+var osExit = os.Exit
+
+// Exitw logs the message with the key-value pairs at error level, and exits with the given exit code. It's the
+// counterpart of Log.Fatalw, which always exits with code 1.
+func Exitw(code int, msg string, keysAndValues ...interface{}) {
+	Log.Errorw(msg, keysAndValues...)
+	Log.Sync()
+	osExit(code)
+}
@@ -0,0 +1,87 @@
+// Structured logging for the commands (text or JSON, with levels)
+package logging
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	FormatText = "text"
+	FormatJson = "json"
+)
+
+// The level of Log, which can be changed at runtime (ToggleDebug). setupLevel is the level given to Setup.
+var (
+	level      = zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	setupLevel = zapcore.InfoLevel
+)
+
+// Log is the shared logger. Until Setup is called it logs text at info level to stdout.
+var Log *zap.SugaredLogger = newLogger(zapcore.NewConsoleEncoder(encoderConfig()), os.Stdout).Sugar()
+
+var format string = FormatText
+
+// toFile is true if Setup was given a RotatingFile (-log-file)
+var toFile bool
+
+// Setup replaces Log with a logger of the given format (text, json) and level (debug, info, warn, error), writing to out
+func Setup(logFormat string, logLevel string, out io.Writer) error {
+	var newLevel zapcore.Level
+	if err := newLevel.UnmarshalText([]byte(logLevel)); err != nil {
+		return fmt.Errorf("invalid log level: %s", logLevel)
+	}
+
+	var encoder zapcore.Encoder
+	switch logFormat {
+	case FormatText:
+		encoder = zapcore.NewConsoleEncoder(encoderConfig())
+	case FormatJson:
+		encoder = zapcore.NewJSONEncoder(encoderConfig())
+	default:
+		return fmt.Errorf("invalid log format: %s", logFormat)
+	}
+
+	Log = newLogger(encoder, out).Sugar()
+	format = logFormat
+	_, toFile = out.(*RotatingFile)
+	setupLevel = newLevel
+	level.SetLevel(newLevel)
+	return nil
+}
+
+// ToggleDebug switches the level of Log between debug and the level of Setup, and returns the new level
+func ToggleDebug() zapcore.Level {
+	newLevel := zapcore.DebugLevel
+	if level.Level() == zapcore.DebugLevel {
+		newLevel = setupLevel
+	}
+	level.SetLevel(newLevel)
+	return newLevel
+}
+
+// IsJson returns true if JSON logging is selected. The colored human-readable output should be skipped then.
+func IsJson() bool {
+	return format == FormatJson
+}
+
+// ToFile returns true if Log writes to a log file (RotatingFile). The colored block output goes to stdout, it should be
+// replaced by a log line then.
+func ToFile() bool {
+	return toFile
+}
+
+func encoderConfig() zapcore.EncoderConfig {
+	config := zap.NewProductionEncoderConfig()
+	config.EncodeTime = zapcore.ISO8601TimeEncoder
+	config.EncodeDuration = zapcore.StringDurationEncoder
+	return config
+}
+
+func newLogger(encoder zapcore.Encoder, out io.Writer) *zap.Logger {
+	return zap.New(zapcore.NewCore(encoder, zapcore.Lock(zapcore.AddSync(out)), level))
+}
@@ -0,0 +1,98 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestSetup(t *testing.T) {
+	defer Setup(FormatText, "info", &bytes.Buffer{})
+
+	if err := Setup("xml", "info", &bytes.Buffer{}); err == nil {
+		t.Error("Expected error for invalid log format")
+	}
+	if err := Setup(FormatJson, "verbose", &bytes.Buffer{}); err == nil {
+		t.Error("Expected error for invalid log level")
+	}
+
+	var out bytes.Buffer
+	if err := Setup(FormatJson, "warn", &out); err != nil {
+		t.Fatal(err)
+	}
+	if !IsJson() {
+		t.Error("Wrong IsJson:", IsJson(), "wanted:", true)
+	}
+
+	Log.Infow("skipped", "block", 1)
+	Log.Warnw("failed tx", "block", 13000000, "hash", "0xabc")
+	Log.Sync()
+
+	line := make(map[string]interface{})
+	if err := json.Unmarshal(out.Bytes(), &line); err != nil {
+		t.Fatal("Invalid JSON log line:", out.String(), err)
+	}
+	if line["msg"] != "failed tx" {
+		t.Error("Wrong msg:", line["msg"], "wanted:", "failed tx")
+	}
+	if line["block"] != float64(13000000) {
+		t.Error("Wrong block:", line["block"], "wanted:", 13000000)
+	}
+	if line["level"] != "warn" {
+		t.Error("Wrong level:", line["level"], "wanted:", "warn")
+	}
+}
+
+func TestExitw(t *testing.T) {
+	defer func() { osExit = os.Exit }()
+	defer Setup(FormatText, "info", &bytes.Buffer{})
+
+	var out bytes.Buffer
+	if err := Setup(FormatJson, "info", &out); err != nil {
+		t.Fatal(err)
+	}
+
+	exitCode := -1
+	osExit = func(code int) { exitCode = code }
+	Exitw(ExitCodeEthNode, "Error connecting to eth node", "node", "ws://localhost:8546")
+
+	if exitCode != ExitCodeEthNode {
+		t.Error("Wrong exit code:", exitCode, "wanted:", ExitCodeEthNode)
+	}
+
+	line := make(map[string]interface{})
+	if err := json.Unmarshal(out.Bytes(), &line); err != nil {
+		t.Fatal("Invalid JSON log line:", out.String(), err)
+	}
+	if line["level"] != "error" {
+		t.Error("Wrong level:", line["level"], "wanted:", "error")
+	}
+}
+
+func TestToggleDebug(t *testing.T) {
+	defer Setup(FormatText, "info", &bytes.Buffer{})
+
+	var out bytes.Buffer
+	if err := Setup(FormatJson, "warn", &out); err != nil {
+		t.Fatal(err)
+	}
+
+	Log.Debugw("skipped")
+	if level := ToggleDebug(); level != zapcore.DebugLevel {
+		t.Error("Wrong level:", level, "wanted:", zapcore.DebugLevel)
+	}
+	Log.Debugw("debug message")
+	if level := ToggleDebug(); level != zapcore.WarnLevel {
+		t.Error("Wrong level:", level, "wanted:", zapcore.WarnLevel)
+	}
+	Log.Infow("skipped")
+	Log.Sync()
+
+	lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
+	if len(lines) != 1 || !bytes.Contains(lines[0], []byte("debug message")) {
+		t.Error("Wrong log output:", out.String(), "wanted only:", "debug message")
+	}
+}
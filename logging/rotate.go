@@ -0,0 +1,155 @@
+// Log file with size-based rotation (-log-file), for daemons without an external logrotate
+package logging
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// RotatingFile is an io.Writer that appends to a file, and renames it to <path>.1 when it would exceed MaxSize. Older
+// backups are shifted to <path>.2, <path>.3, ... and removed beyond MaxBackups or when older than MaxAge
+// (at the rotation).
+type RotatingFile struct {
+	Path       string
+	MaxSize    int64         // in bytes
+	MaxBackups int           // 0 keeps all backups
+	MaxAge     time.Duration // 0 keeps all backups
+
+	lock sync.Mutex
+	file *os.File
+	size int64
+}
+
+// NewRotatingFile opens (or creates) the log file at path. maxSizeMB is the maximum size in megabytes before it's
+// rotated.
+func NewRotatingFile(path string, maxSizeMB int, maxBackups int, maxAge time.Duration) (*RotatingFile, error) {
+	if maxSizeMB <= 0 {
+		return nil, fmt.Errorf("invalid max log file size: %d MB", maxSizeMB)
+	}
+	if maxBackups < 0 {
+		return nil, fmt.Errorf("invalid max log file backups: %d", maxBackups)
+	}
+	if maxAge < 0 {
+		return nil, fmt.Errorf("invalid max log file age: %s", maxAge)
+	}
+
+	f := &RotatingFile{Path: path, MaxSize: int64(maxSizeMB) * 1024 * 1024, MaxBackups: maxBackups, MaxAge: maxAge}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+// Write appends p to the file, after rotating it if p doesn't fit anymore. A single write larger than MaxSize is
+// written to an empty file.
+func (f *RotatingFile) Write(p []byte) (n int, err error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if f.size > 0 && f.size+int64(len(p)) > f.MaxSize {
+		if err = f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err = f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Sync flushes the file (called by the logger before exiting)
+func (f *RotatingFile) Sync() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	return f.file.Sync()
+}
+
+func (f *RotatingFile) Close() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+func (f *RotatingFile) backupPath(i int) string {
+	return fmt.Sprintf("%s.%d", f.Path, i)
+}
+
+// rotate closes the file, shifts the backups by one and opens a new file. Must be called with the lock held.
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+
+	// Find the number of existing backups, and shift them starting with the oldest (the last one is removed if
+	// MaxBackups is reached)
+	count := 0
+	for {
+		if _, err := os.Stat(f.backupPath(count + 1)); err != nil {
+			break
+		}
+		count++
+	}
+	for i := count; i >= 1; i-- {
+		if f.MaxBackups > 0 && i >= f.MaxBackups {
+			os.Remove(f.backupPath(i))
+			continue
+		}
+		if err := os.Rename(f.backupPath(i), f.backupPath(i+1)); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(f.Path, f.backupPath(1)); err != nil {
+		return err
+	}
+
+	f.removeOldBackups()
+	return f.open()
+}
+
+// removeOldBackups removes the backups that were last written more than MaxAge ago. The backups are ordered by age, so
+// all backups after the first one that is too old are removed as well.
+func (f *RotatingFile) removeOldBackups() {
+	if f.MaxAge == 0 {
+		return
+	}
+	tooOld := false
+	for i := 1; ; i++ {
+		info, err := os.Stat(f.backupPath(i))
+		if err != nil {
+			return
+		}
+		if tooOld || time.Since(info.ModTime()) > f.MaxAge {
+			tooOld = true
+			os.Remove(f.backupPath(i))
+		}
+	}
+}
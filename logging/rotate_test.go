@@ -0,0 +1,97 @@
+package logging
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRotatingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "block-watch.log")
+
+	if _, err := NewRotatingFile(path, 0, 1, 0); err == nil {
+		t.Error("Expected error for invalid max size")
+	}
+
+	f, err := NewRotatingFile(path, 1, 2, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.MaxSize = 10 // bytes, to rotate in the test
+
+	for _, line := range []string{"line 1\n", "line 2\n", "line 3\n", "line 4\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f.Close()
+
+	// Every line is rotated, only 2 backups are kept
+	for path, want := range map[string]string{path: "line 4\n", path + ".1": "line 3\n", path + ".2": "line 2\n"} {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != want {
+			t.Error("Wrong content of", path, ":", string(content), "wanted:", want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); err == nil {
+		t.Error("Expected backup 3 to be removed")
+	}
+
+	// Appends to an existing file
+	f, err = NewRotatingFile(path, 1, 2, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.size != 7 {
+		t.Error("Wrong size:", f.size, "wanted:", 7)
+	}
+
+	// Backups older than MaxAge are removed at the next rotation
+	old := time.Now().Add(-2 * time.Hour)
+	os.Chtimes(path+".1", old, old) // becomes backup 2
+	f.MaxSize = 10
+	f.Write([]byte("line 5\n"))
+	f.Close()
+	if _, err := os.Stat(path + ".2"); err == nil {
+		t.Error("Expected old backup to be removed")
+	}
+	if _, err := f.Write([]byte("closed\n")); err == nil {
+		t.Error("Expected error writing to a closed file")
+	}
+}
+
+func TestSetupToFile(t *testing.T) {
+	defer Setup(FormatText, "info", &bytes.Buffer{})
+
+	dir, err := ioutil.TempDir("", "rotate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	f, err := NewRotatingFile(filepath.Join(dir, "test.log"), 1, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	Setup(FormatText, "info", f)
+	if !ToFile() {
+		t.Error("Wrong ToFile:", ToFile(), "wanted:", true)
+	}
+	Setup(FormatText, "info", &bytes.Buffer{})
+	if ToFile() {
+		t.Error("Wrong ToFile:", ToFile(), "wanted:", false)
+	}
+}
@@ -0,0 +1,24 @@
+//go:build !windows
+// +build !windows
+
+// Toggling debug logging at runtime with SIGUSR1
+package logging
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// ToggleDebugOnSignal toggles debug logging (ToggleDebug) whenever the process receives SIGUSR1, i.e. to capture
+// detailed logs of a running watcher with `kill -USR1 <pid>`
+func ToggleDebugOnSignal() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1)
+	go func() {
+		for range signals {
+			newLevel := ToggleDebug()
+			Log.Warnw("Log level changed (SIGUSR1)", "level", newLevel.String())
+		}
+	}()
+}
@@ -0,0 +1,5 @@
+// Toggling debug logging at runtime with SIGUSR1 (not available on Windows)
+package logging
+
+// ToggleDebugOnSignal does nothing on Windows, which has no SIGUSR1
+func ToggleDebugOnSignal() {}